@@ -0,0 +1,568 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hubenschmidt/claude-dag/internal/agent"
+	"github.com/hubenschmidt/claude-dag/internal/artifact"
+	"github.com/hubenschmidt/claude-dag/internal/control"
+	"github.com/hubenschmidt/claude-dag/internal/orchestrator"
+	"github.com/hubenschmidt/claude-dag/internal/run"
+	"github.com/hubenschmidt/claude-dag/internal/tmux"
+)
+
+func cmdStatus(args []string) int {
+	fs := newFlagSet("status")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 1 {
+		return usageError(fs, "too many arguments")
+	}
+
+	r, err := run.Load(fs.Arg(0))
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	live := "no"
+	if r.Outcome == run.OutcomeRunning && tmux.HasSession(sessionName) {
+		live = "yes (swarm attach)"
+	}
+	fmt.Printf("Run:      %s\n", r.ID)
+	fmt.Printf("Goal:     %s\n", r.Goal)
+	fmt.Printf("Outcome:  %s\n", r.Outcome)
+	fmt.Printf("Duration: %s\n", r.Duration())
+	fmt.Printf("Live:     %s\n\n", live)
+
+	g, err := orchestrator.LoadGraph(r.StatePath())
+	if err != nil {
+		fmt.Println("no checkpoint yet")
+		return exitOK
+	}
+	orchestrator.PrintGraph(os.Stdout, g)
+	orchestrator.PrintBlocked(os.Stdout, g)
+	return exitOK
+}
+
+func cmdGraph(args []string) int {
+	fs := newFlagSet("graph")
+	format := fs.String("format", "dot", "output format: dot or mermaid")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 1 {
+		return usageError(fs, "too many arguments")
+	}
+
+	write, ok := graphWriters[*format]
+	if !ok {
+		return usageError(fs, fmt.Sprintf("unknown format %q", *format))
+	}
+	r, err := run.Load(fs.Arg(0))
+	if err != nil {
+		return fail("%v", err)
+	}
+	g, err := orchestrator.LoadGraph(r.StatePath())
+	if err != nil {
+		return fail("run %s has no checkpoint: %v", r.ID, err)
+	}
+	write(os.Stdout, g)
+	return exitOK
+}
+
+// graphWriters maps --format values to exporters.
+var graphWriters = map[string]func(io.Writer, *orchestrator.Graph){
+	"dot":     orchestrator.WriteDOT,
+	"mermaid": orchestrator.WriteMermaid,
+}
+
+func cmdAttach(args []string) int {
+	fs := newFlagSet("attach")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, "unexpected arguments: "+strings.Join(fs.Args(), " "))
+	}
+	if !tmux.HasSession(sessionName) {
+		return fail("no swarm session is running")
+	}
+	return attachSession()
+}
+
+func cmdRetry(args []string) int {
+	fs := newFlagSet("retry")
+	runID := fs.String("run", "", "run to retry in (default: latest)")
+	feedback := fs.String("feedback", "", "feedback to give the agent on its next attempt")
+	noResume := fs.Bool("no-resume", false, "only re-queue the task; don't resume the run")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		return usageError(fs, "expected exactly one task ID")
+	}
+	taskID := fs.Arg(0)
+
+	r, err := run.Load(*runID)
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	// A live run is retried in place through its control socket.
+	if r.Outcome == run.OutcomeRunning && tmux.HasSession(sessionName) {
+		return sendControl(r, control.Request{Command: control.CmdRetry, Task: taskID, Feedback: *feedback})
+	}
+
+	g, err := orchestrator.LoadGraph(r.StatePath())
+	if err != nil {
+		return fail("run %s has no checkpoint: %v", r.ID, err)
+	}
+
+	artifact.BaseDir = r.ArtifactsDir()
+	if err := orchestrator.RetryTask(g, taskID, *feedback); err != nil {
+		return fail("%v", err)
+	}
+	if err := g.Save(r.StatePath()); err != nil {
+		return fail("save checkpoint: %v", err)
+	}
+	fmt.Printf("re-queued %s in run %s\n", taskID, r.ID)
+
+	if *noResume {
+		return exitOK
+	}
+	return cmdResume([]string{r.ID})
+}
+
+// cmdCancel stops the live run the way Ctrl-C in window 0 does: launches
+// stop, agents wrap up and the run is checkpointed. --force kills the
+// session at once instead.
+func cmdCancel(args []string) int {
+	fs := newFlagSet("cancel")
+	force := fs.Bool("force", false, "kill the session at once instead of letting agents wrap up")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, "unexpected arguments: "+strings.Join(fs.Args(), " "))
+	}
+	if !tmux.HasSession(sessionName) {
+		return fail("no swarm session is running")
+	}
+	if !*force {
+		r, err := run.Load("")
+		if err != nil {
+			return fail("%v", err)
+		}
+		if code := sendControl(r, control.Request{Command: control.CmdStop}); code != exitOK {
+			fmt.Fprintln(os.Stderr, "swarm: use --force to kill the session")
+			return code
+		}
+		return exitOK
+	}
+	if err := tmux.KillSession(sessionName); err != nil {
+		return fail("kill session: %v", err)
+	}
+
+	r, err := run.Load("")
+	if err == nil && r.Outcome == run.OutcomeRunning {
+		_ = r.Finish(run.OutcomeCancelled, fmt.Errorf("cancelled by user"))
+		fmt.Printf("cancelled run %s\n", r.ID)
+		return exitOK
+	}
+	fmt.Println("killed swarm session")
+	return exitOK
+}
+
+func cmdCtl(args []string) int {
+	fs := newFlagSet("ctl")
+	runID := fs.String("run", "", "run to control (default: latest)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: swarm ctl [--run id] <command> [arguments]")
+		fmt.Fprintln(fs.Output(), "\ncommands:")
+		fmt.Fprintln(fs.Output(), "  pause                          stop launching new tasks")
+		fmt.Fprintln(fs.Output(), "  resume                         resume launching tasks")
+		fmt.Fprintln(fs.Output(), "  retry <task> [feedback...]     re-queue a task, killing its pane if running")
+		fmt.Fprintln(fs.Output(), "  cancel <task>                  kill a task's pane and stop it")
+		fmt.Fprintln(fs.Output(), "  concurrency <n>                set the max concurrent tasks")
+		fmt.Fprintln(fs.Output(), "  approve <task>                 sign off on a task awaiting approval")
+		fmt.Fprintln(fs.Output(), "  reject <task> <feedback...>    send a task awaiting approval back for rework")
+		fmt.Fprintln(fs.Output(), "  add [--depends a,b] [--approval] <id> <role> <description...>")
+		fmt.Fprintln(fs.Output(), "                                 add a task to the graph")
+	}
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() == 0 {
+		return usageError(fs, "missing command")
+	}
+
+	req, err := parseCtl(fs.Args())
+	if err != nil {
+		return usageError(fs, err.Error())
+	}
+	r, err := run.Load(*runID)
+	if err != nil {
+		return fail("%v", err)
+	}
+	return sendControl(r, req)
+}
+
+// parseCtl turns "swarm ctl" arguments into a control request.
+func parseCtl(args []string) (control.Request, error) {
+	req := control.Request{Command: args[0]}
+	rest := args[1:]
+
+	switch req.Command {
+	case control.CmdPause, control.CmdResume:
+		if len(rest) != 0 {
+			return req, fmt.Errorf("%s takes no arguments", req.Command)
+		}
+	case control.CmdRetry:
+		if len(rest) == 0 {
+			return req, fmt.Errorf("retry needs a task ID")
+		}
+		req.Task = rest[0]
+		req.Feedback = strings.Join(rest[1:], " ")
+	case control.CmdCancel, control.CmdApprove:
+		if len(rest) != 1 {
+			return req, fmt.Errorf("%s needs exactly one task ID", req.Command)
+		}
+		req.Task = rest[0]
+	case control.CmdReject:
+		if len(rest) < 2 {
+			return req, fmt.Errorf("reject needs a task ID and feedback")
+		}
+		req.Task = rest[0]
+		req.Feedback = strings.Join(rest[1:], " ")
+	case control.CmdConcurrency:
+		if len(rest) != 1 {
+			return req, fmt.Errorf("concurrency needs a number")
+		}
+		n, err := strconv.Atoi(rest[0])
+		if err != nil {
+			return req, fmt.Errorf("concurrency: %v", err)
+		}
+		req.Value = n
+	case control.CmdAdd:
+		addFlags := flag.NewFlagSet("add", flag.ContinueOnError)
+		depends := addFlags.String("depends", "", "comma-separated task IDs the new task waits on")
+		approval := addFlags.Bool("approval", false, "require human approval after review")
+		if err := addFlags.Parse(rest); err != nil {
+			return req, err
+		}
+		if addFlags.NArg() < 3 {
+			return req, fmt.Errorf("add needs <id> <role> <description>")
+		}
+		req.Task = addFlags.Arg(0)
+		req.Role = addFlags.Arg(1)
+		req.Description = strings.Join(addFlags.Args()[2:], " ")
+		if *depends != "" {
+			req.DependsOn = strings.Split(*depends, ",")
+		}
+		req.RequiresApproval = *approval
+	default:
+		return req, fmt.Errorf("unknown ctl command %q", req.Command)
+	}
+	return req, nil
+}
+
+// sendControl delivers req to run r's control socket and prints the reply.
+func sendControl(r *run.Run, req control.Request) int {
+	if r.Outcome != run.OutcomeRunning {
+		return fail("run %s is not live (%s)", r.ID, r.Outcome)
+	}
+	msg, err := control.Send(r.ControlPath(), req)
+	if err != nil {
+		return fail("%v", err)
+	}
+	fmt.Println(msg)
+	return exitOK
+}
+
+func cmdShow(args []string) int {
+	fs := newFlagSet("show")
+	runID := fs.String("run", "", "run the task belongs to (default: latest)")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		return usageError(fs, "expected exactly one task ID")
+	}
+
+	r, err := run.Load(*runID)
+	if err != nil {
+		return fail("%v", err)
+	}
+	g, err := orchestrator.LoadGraph(r.StatePath())
+	if err != nil {
+		return fail("run %s has no checkpoint: %v", r.ID, err)
+	}
+	t, ok := g.Get(fs.Arg(0))
+	if !ok {
+		return fail("no task %s in run %s", fs.Arg(0), r.ID)
+	}
+	orchestrator.PrintTask(os.Stdout, t)
+	return exitOK
+}
+
+func cmdLogs(args []string) int {
+	fs := newFlagSet("logs")
+	runID := fs.String("run", "", "run to read logs from (default: latest)")
+	follow := fs.Bool("f", false, "keep printing as the log grows")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 {
+		return usageError(fs, "expected exactly one task ID (or \"orchestrator\")")
+	}
+
+	r, err := run.Load(*runID)
+	if err != nil {
+		return fail("%v", err)
+	}
+	path := filepath.Join(r.LogsDir(), fs.Arg(0)+".log")
+	if _, err := os.Stat(path); err != nil {
+		return fail("no log for %s in run %s", fs.Arg(0), r.ID)
+	}
+
+	tailArgs := []string{"-n", "+1", path}
+	if *follow {
+		tailArgs = append([]string{"-f"}, tailArgs...)
+	}
+	tail := exec.Command("tail", tailArgs...)
+	tail.Stdout = os.Stdout
+	tail.Stderr = os.Stderr
+	if err := tail.Run(); err != nil {
+		return fail("read log: %v", err)
+	}
+	return exitOK
+}
+
+func cmdClean(args []string) int {
+	fs := newFlagSet("clean")
+	keep := fs.Int("keep", 5, "number of most recent runs to keep")
+	all := fs.Bool("all", false, "delete every run, including the latest")
+	dryRun := fs.Bool("n", false, "print what would be deleted without deleting")
+	force := fs.Bool("force", false, "remove brownfield worktrees even if they hold uncommitted changes")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, "unexpected arguments: "+strings.Join(fs.Args(), " "))
+	}
+	if *all {
+		*keep = 0
+	}
+
+	runs, err := run.List()
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	live := tmux.HasSession(sessionName)
+	removed := 0
+	for i, r := range runs {
+		if i < *keep {
+			continue
+		}
+		if live && r.Outcome == run.OutcomeRunning {
+			fmt.Printf("skipping live run %s\n", r.ID)
+			continue
+		}
+		removed++
+		if *dryRun {
+			fmt.Printf("would remove %s (%s, %s)\n", r.ID, r.Outcome, r.Goal)
+			continue
+		}
+		fmt.Printf("removing %s (%s, %s)\n", r.ID, r.Outcome, r.Goal)
+		if r.Target != nil {
+			// Keep the run if its worktree can't go: deleting the run dir
+			// would delete the worktree's uncommitted changes with it.
+			if err := r.Target.Remove(*force); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v; keeping it (use --force to remove anyway)\n", r.ID, err)
+				removed--
+				continue
+			}
+		}
+		if err := os.RemoveAll(r.Dir()); err != nil {
+			return fail("remove %s: %v", r.Dir(), err)
+		}
+	}
+
+	// Drop the latest link once its target is gone.
+	if latest := filepath.Join(run.RootDir, "latest"); !*dryRun {
+		if _, err := os.Stat(latest); os.IsNotExist(err) {
+			_ = os.Remove(latest)
+		}
+	}
+
+	if *dryRun {
+		fmt.Printf("%d run(s) would be removed\n", removed)
+		return exitOK
+	}
+	fmt.Printf("%d run(s) removed\n", removed)
+	return exitOK
+}
+
+func cmdDoctor(args []string) int {
+	fs := newFlagSet("doctor")
+	promptDir := promptDirFlag(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 0 {
+		return usageError(fs, "unexpected arguments: "+strings.Join(fs.Args(), " "))
+	}
+	if _, err := applyPromptDir(*promptDir); err != nil {
+		return fail("%v", err)
+	}
+
+	healthy := true
+	check := func(name string, ok bool, detail string) {
+		mark := "ok  "
+		if !ok {
+			mark = "FAIL"
+			healthy = false
+		}
+		fmt.Printf("[%s] %-10s %s\n", mark, name, detail)
+	}
+
+	for _, bin := range []string{"tmux", "claude"} {
+		path, err := exec.LookPath(bin)
+		if err != nil {
+			check(bin, false, "not found in PATH")
+			continue
+		}
+		check(bin, true, strings.TrimSpace(path+" "+commandVersion(bin)))
+	}
+
+	sources, err := agent.ListPrompts()
+	if err != nil {
+		check("prompts", false, err.Error())
+	}
+	for _, src := range sources {
+		check("prompt", true, fmt.Sprintf("%s <- %s", src.Name, src.Path))
+	}
+
+	if err := os.MkdirAll(run.RootDir, 0o755); err != nil {
+		check("runs dir", false, err.Error())
+	} else {
+		probe := filepath.Join(run.RootDir, ".doctor")
+		err := os.WriteFile(probe, nil, 0o644)
+		_ = os.Remove(probe)
+		check("runs dir", err == nil, run.RootDir)
+	}
+
+	if tmux.HasSession(sessionName) {
+		check("session", true, sessionName+" is running (swarm attach)")
+	} else {
+		check("session", true, "no session running")
+	}
+
+	if !healthy {
+		return exitError
+	}
+	return exitOK
+}
+
+// commandVersion returns the first line of "<bin> --version" (tmux uses -V).
+func commandVersion(bin string) string {
+	opt := "--version"
+	if bin == "tmux" {
+		opt = "-V"
+	}
+	out, err := exec.Command(bin, opt).Output()
+	if err != nil {
+		return ""
+	}
+	line, _, _ := strings.Cut(string(out), "\n")
+	return "(" + strings.TrimSpace(line) + ")"
+}
+
+func cmdPrompts(args []string) int {
+	fs := newFlagSet("prompts")
+	promptDir := promptDirFlag(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() != 1 || fs.Arg(0) != "list" {
+		return usageError(fs, "expected: prompts list")
+	}
+	if _, err := applyPromptDir(*promptDir); err != nil {
+		return fail("%v", err)
+	}
+
+	sources, err := agent.ListPrompts()
+	if err != nil {
+		return fail("list prompts: %v", err)
+	}
+	for _, src := range sources {
+		fmt.Printf("%-12s %s\n", src.Name, src.Path)
+	}
+	return exitOK
+}
+
+// cmdRuns handles the "runs" subcommand: browsing past run directories.
+func cmdRuns(args []string) int {
+	fs := newFlagSet("runs")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	switch {
+	case fs.NArg() == 1 && fs.Arg(0) == "list":
+		return listRuns()
+	case fs.NArg() == 2 && fs.Arg(0) == "show":
+		return showRun(fs.Arg(1))
+	default:
+		return usageError(fs, "expected: runs list | runs show <run-id>")
+	}
+}
+
+func listRuns() int {
+	runs, err := run.List()
+	if err != nil {
+		return fail("list runs: %v", err)
+	}
+	if len(runs) == 0 {
+		fmt.Println("no runs recorded in", run.RootDir)
+		return exitOK
+	}
+	fmt.Printf("%-20s %-10s %-10s %s\n", "Run", "Outcome", "Duration", "Goal")
+	fmt.Println(strings.Repeat("-", 80))
+	for _, r := range runs {
+		fmt.Printf("%-20s %-10s %-10s %s\n", r.ID, r.Outcome, r.Duration(), r.Goal)
+	}
+	return exitOK
+}
+
+func showRun(id string) int {
+	r, err := run.Load(id)
+	if err != nil {
+		return fail("load run: %v", err)
+	}
+	fmt.Printf("Run:       %s\n", r.ID)
+	fmt.Printf("Goal:      %s\n", r.Goal)
+	fmt.Printf("Outcome:   %s\n", r.Outcome)
+	fmt.Printf("Started:   %s\n", r.StartedAt.Format(time.RFC3339))
+	fmt.Printf("Duration:  %s\n", r.Duration())
+	if r.Error != "" {
+		fmt.Printf("Error:     %s\n", r.Error)
+	}
+	fmt.Printf("Directory: %s\n", r.Dir())
+
+	summary, err := os.ReadFile(r.SummaryPath())
+	if err != nil {
+		return exitOK
+	}
+	fmt.Print(string(summary))
+	return exitOK
+}
@@ -1,141 +1,168 @@
 package main
 
 import (
-	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"io"
 	"os"
-	"os/exec"
-	"os/signal"
-	"strings"
-	"time"
+	"path/filepath"
 
 	"github.com/hubenschmidt/claude-dag/internal/agent"
-	"github.com/hubenschmidt/claude-dag/internal/orchestrator"
-	"github.com/hubenschmidt/claude-dag/internal/tmux"
 )
 
 const sessionName = "claude-dag"
 
-func main() {
-	goal := strings.Join(os.Args[1:], " ")
-	if goal == "" {
-		fmt.Fprintln(os.Stderr, "usage: swarm <goal description>")
-		fmt.Fprintln(os.Stderr, "  example: swarm Build a todo app with user accounts")
-		os.Exit(1)
-	}
+// Exit codes shared by every subcommand.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
 
-	preflight()
+// command is one swarm subcommand.
+type command struct {
+	name    string
+	args    string // argument synopsis shown in help
+	summary string
+	run     func(args []string) int
+}
 
-	// If SWARM_INSIDE=1, we're already in the tmux session — run the orchestrator.
-	// Otherwise, create the tmux session and re-exec ourselves inside it.
-	if os.Getenv("SWARM_INSIDE") != "1" {
-		launchInTmux(goal)
-		return
+var commands []command
+
+func init() {
+	// Assigned in init because cmdHelp refers back to the table.
+	commands = []command{
+		{"run", "[flags] <goal>", "start a new run for goal (also: swarm <goal>)", cmdRun},
+		{"resume", "[flags] [run-id]", "continue a stopped run from its checkpoint", cmdResume},
+		{"status", "[run-id]", "show the task table of a run", cmdStatus},
+		{"attach", "", "attach to the live tmux session", cmdAttach},
+		{"retry", "[flags] <task>", "re-queue a task with a fresh attempt budget", cmdRetry},
+		{"cancel", "", "stop the live run gracefully (--force to kill its session)", cmdCancel},
+		{"ctl", "<command> [arguments]", "send a command to the live orchestrator", cmdCtl},
+		{"show", "[--run run-id] <task>", "show a task's details and attempt history", cmdShow},
+		{"graph", "[--format dot|mermaid] [run-id]", "export a run's task graph", cmdGraph},
+		{"logs", "[flags] <task>", "print an agent's pane transcript", cmdLogs},
+		{"clean", "[flags]", "delete old run directories", cmdClean},
+		{"doctor", "[flags]", "check that the swarm can run here", cmdDoctor},
+		{"prompts", "list", "show which file each role's prompt comes from", cmdPrompts},
+		{"runs", "list | show <run-id>", "browse past runs", cmdRuns},
+		{"help", "[command]", "show help for a command", cmdHelp},
 	}
-
-	runOrchestrator(goal)
 }
 
-// launchInTmux creates a tmux session running this binary as pane 0, then attaches.
-func launchInTmux(goal string) {
-	_ = tmux.KillSession(sessionName)
+func main() {
+	os.Exit(dispatch(os.Args[1:]))
+}
 
-	bin, err := os.Executable()
-	if err != nil {
-		log.Fatalf("resolve executable: %v", err)
+// dispatch runs the named subcommand. Anything that isn't a subcommand is
+// treated as the goal of an implicit "run".
+func dispatch(args []string) int {
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return exitUsage
 	}
-
-	// Create session with the orchestrator as pane 0's command
-	innerCmd := fmt.Sprintf("SWARM_INSIDE=1 %s %s", shellEscape(bin), shellEscape(goal))
-	if err := tmux.CreateSessionWithCmd(sessionName, innerCmd); err != nil {
-		log.Fatalf("create tmux session: %v", err)
+	if args[0] == "-h" || args[0] == "--help" {
+		usage(os.Stdout)
+		return exitOK
 	}
-
-	// Keep dead panes visible so user can read agent output
-	_ = tmux.ConfigureSession(sessionName)
-
-	// Attach and immediately open the session/window tree view
-	attach := exec.Command("tmux", "attach", "-t", sessionName, ";", "choose-tree", "-w")
-	attach.Stdin = os.Stdin
-	attach.Stdout = os.Stdout
-	attach.Stderr = os.Stderr
-	if err := attach.Run(); err != nil {
-		log.Fatalf("tmux attach: %v", err)
+	if c, ok := lookup(args[0]); ok {
+		return c.run(args[1:])
 	}
+	return cmdRun(args)
 }
 
-func runOrchestrator(goal string) {
-	agents := []agent.Agent{
-		agent.NewArchitect(),
-		agent.NewBackend(),
-		agent.NewFrontend(),
-		agent.NewReviewer(),
+func lookup(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
 	}
+	return command{}, false
+}
 
-	orch := orchestrator.New(sessionName, agents)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
-	defer cancel()
-
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, os.Interrupt)
-	go func() {
-		<-sigCh
-		log.Println("interrupted, killing tmux session...")
-		_ = tmux.KillSession(sessionName)
-		os.Exit(1)
-	}()
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: swarm <command> [arguments]")
+	fmt.Fprintln(w, "       swarm <goal description>")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-9s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "example: swarm Build a todo app with user accounts")
+	fmt.Fprintln(w, "run 'swarm help <command>' for a command's flags")
+}
 
-	start := time.Now()
-	log.Println("=== Claude DAG ===")
-	log.Printf("Goal: %s", goal)
+func cmdHelp(args []string) int {
+	if len(args) == 0 {
+		usage(os.Stdout)
+		return exitOK
+	}
+	c, ok := lookup(args[0])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+		return exitUsage
+	}
+	return c.run([]string{"-h"})
+}
 
-	err := orch.Run(ctx, goal)
-	elapsed := time.Since(start)
+// newFlagSet returns a flag set whose usage output names the subcommand.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		c, _ := lookup(name)
+		fmt.Fprintf(fs.Output(), "usage: swarm %s %s\n\n%s\n", c.name, c.args, c.summary)
+		var hasFlags bool
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(fs.Output(), "\nflags:")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
 
+// parseFlags parses args into fs, mapping -h to a clean exit and any other
+// parse failure to a usage error. ok is false when the caller should return code.
+func parseFlags(fs *flag.FlagSet, args []string) (code int, ok bool) {
+	err := fs.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK, false
+	}
 	if err != nil {
-		log.Printf("swarm failed after %s: %v", elapsed, err)
-		printSummary(orch.Graph())
-		fmt.Println("\nPress Enter to exit...")
-		fmt.Scanln()
-		os.Exit(1)
+		return exitUsage, false
 	}
+	return exitOK, true
+}
 
-	log.Printf("swarm completed in %s", elapsed)
-	printSummary(orch.Graph())
-	fmt.Println("\nPress Enter to exit...")
-	fmt.Scanln()
+// usageError prints msg and the subcommand usage, returning exitUsage.
+func usageError(fs *flag.FlagSet, msg string) int {
+	fmt.Fprintln(os.Stderr, msg)
+	fs.Usage()
+	return exitUsage
 }
 
-func preflight() {
-	missing := []string{}
-	for _, bin := range []string{"tmux", "claude"} {
-		if _, err := exec.LookPath(bin); err != nil {
-			missing = append(missing, bin)
-		}
-	}
-	if len(missing) == 0 {
-		return
-	}
-	fmt.Fprintf(os.Stderr, "required commands not found: %s\n", strings.Join(missing, ", "))
-	fmt.Fprintln(os.Stderr, "install with: sudo apt-get install tmux  (or brew install tmux)")
-	fmt.Fprintln(os.Stderr, "claude: https://docs.anthropic.com/en/docs/claude-code")
-	os.Exit(1)
+// promptDirFlag registers --prompt-dir on fs.
+func promptDirFlag(fs *flag.FlagSet) *string {
+	return fs.String("prompt-dir", "", "directory of <role>.md prompt overrides (searched first)")
 }
 
-func printSummary(g *orchestrator.Graph) {
-	fmt.Println("\n=== Task Summary ===")
-	for _, t := range g.Tasks() {
-		pane := ""
-		if t.PaneID != "" {
-			pane = fmt.Sprintf(" [pane %s]", t.PaneID)
+// applyPromptDir makes dir absolute and installs the prompt search path.
+// It returns the absolute dir so it can be forwarded into the tmux session.
+func applyPromptDir(dir string) (string, error) {
+	if dir != "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return "", fmt.Errorf("resolve prompt dir: %w", err)
 		}
-		fmt.Printf("  [%s] %s (%s)%s: %s\n", t.Status, t.ID, t.Role, pane, t.Description)
+		dir = abs
 	}
-	fmt.Println("\nArtifacts written to ./artifacts/")
+	agent.SetPromptDirs(agent.DefaultPromptDirs(dir))
+	return dir, nil
 }
 
-func shellEscape(s string) string {
-	return "'" + strings.ReplaceAll(s, "'", "'\"'\"'") + "'"
+func fail(format string, args ...any) int {
+	fmt.Fprintf(os.Stderr, "swarm: "+format+"\n", args...)
+	return exitError
 }
@@ -0,0 +1,487 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/hubenschmidt/claude-dag/internal/agent"
+	"github.com/hubenschmidt/claude-dag/internal/artifact"
+	"github.com/hubenschmidt/claude-dag/internal/brownfield"
+	"github.com/hubenschmidt/claude-dag/internal/cache"
+	"github.com/hubenschmidt/claude-dag/internal/config"
+	"github.com/hubenschmidt/claude-dag/internal/control"
+	"github.com/hubenschmidt/claude-dag/internal/orchestrator"
+	"github.com/hubenschmidt/claude-dag/internal/run"
+	"github.com/hubenschmidt/claude-dag/internal/tmux"
+)
+
+func cmdRun(args []string) int {
+	fs := newFlagSet("run")
+	promptDir := promptDirFlag(fs)
+	planOnly := fs.Bool("plan-only", false, "stop after the architect's plan is expanded and print the DAG")
+	approvePlan := fs.Bool("approve-plan", false, "wait for approval of the expanded plan before building")
+	noCache := noCacheFlag(fs)
+	repo := fs.String("repo", "", "change the existing git repository at `path` instead of generating a new project")
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+
+	goal := strings.Join(fs.Args(), " ")
+	if goal == "" {
+		return usageError(fs, "missing goal")
+	}
+	if *planOnly && *approvePlan {
+		return usageError(fs, "--plan-only and --approve-plan are mutually exclusive")
+	}
+	mode := orchestrator.PlanAuto
+	switch {
+	case *planOnly:
+		mode = orchestrator.PlanOnly
+	case *approvePlan:
+		mode = orchestrator.PlanApprove
+	}
+
+	dir, err := applyPromptDir(*promptDir)
+	if err != nil {
+		return fail("%v", err)
+	}
+	if !preflight() {
+		return exitError
+	}
+	if *repo != "" {
+		if *repo, err = brownfield.RepoRoot(*repo); err != nil {
+			return fail("%v", err)
+		}
+	}
+
+	// If SWARM_INSIDE=1, we're already in the tmux session — run the orchestrator.
+	// Otherwise, create the tmux session and re-exec ourselves inside it.
+	if os.Getenv("SWARM_INSIDE") != "1" {
+		inner := withPromptDir([]string{"run"}, dir)
+		if *planOnly {
+			inner = append(inner, "--plan-only")
+		}
+		if *approvePlan {
+			inner = append(inner, "--approve-plan")
+		}
+		if *repo != "" {
+			inner = append(inner, "--repo", *repo)
+		}
+		if err := claimSession(); err != nil {
+			return fail("%v", err)
+		}
+		return launchInTmux(withNoCache(inner, *noCache), goal)
+	}
+
+	r, err := run.New(goal)
+	if err != nil {
+		return fail("create run: %v", err)
+	}
+	if *repo != "" {
+		if err := checkoutTarget(r, *repo); err != nil {
+			_ = r.Finish(run.OutcomeFailed, err)
+			return fail("%v", err)
+		}
+	}
+	return runOrchestrator(r, nil, mode, !*noCache)
+}
+
+func cmdResume(args []string) int {
+	fs := newFlagSet("resume")
+	promptDir := promptDirFlag(fs)
+	noCache := noCacheFlag(fs)
+	if code, ok := parseFlags(fs, args); !ok {
+		return code
+	}
+	if fs.NArg() > 1 {
+		return usageError(fs, "too many arguments")
+	}
+
+	r, err := run.Load(fs.Arg(0))
+	if err != nil {
+		return fail("%v", err)
+	}
+	g, err := orchestrator.LoadGraph(r.StatePath())
+	if err != nil {
+		return fail("run %s has no checkpoint: %v", r.ID, err)
+	}
+	dir, err := applyPromptDir(*promptDir)
+	if err != nil {
+		return fail("%v", err)
+	}
+	if !preflight() {
+		return exitError
+	}
+
+	if os.Getenv("SWARM_INSIDE") != "1" {
+		if err := claimSession(); err != nil {
+			return fail("%v", err)
+		}
+		return launchInTmux(withNoCache(withPromptDir([]string{"resume"}, dir), *noCache), r.ID)
+	}
+
+	if err := r.Reopen(); err != nil {
+		return fail("reopen run: %v", err)
+	}
+	return runOrchestrator(r, g, orchestrator.PlanAuto, !*noCache)
+}
+
+// withPromptDir appends --prompt-dir to a subcommand's inner arguments.
+func withPromptDir(args []string, dir string) []string {
+	if dir == "" {
+		return args
+	}
+	return append(args, "--prompt-dir", dir)
+}
+
+// checkoutTarget checks the repository at repo out into run r's artifacts
+// on a branch of its own, and maps it for the architect.
+func checkoutTarget(r *run.Run, repo string) error {
+	worktree, err := filepath.Abs(r.WorktreeDir())
+	if err != nil {
+		return fmt.Errorf("resolve worktree dir: %w", err)
+	}
+	target, err := brownfield.Checkout(repo, "swarm/"+r.ID, worktree)
+	if err != nil {
+		return err
+	}
+	r.Target = target
+	if err := r.Save(); err != nil {
+		return fmt.Errorf("save run metadata: %w", err)
+	}
+
+	m, err := brownfield.BuildMap(worktree)
+	if err != nil {
+		return err
+	}
+	mapDir := filepath.Join(r.ArtifactsDir(), "repomap")
+	if err := os.MkdirAll(mapDir, 0o755); err != nil {
+		return fmt.Errorf("mkdir %s: %w", mapDir, err)
+	}
+	return os.WriteFile(filepath.Join(mapDir, "repo-map.md"), []byte(m.Markdown()), 0o644)
+}
+
+// noCacheFlag registers --no-cache on fs.
+func noCacheFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("no-cache", false, "run every task, ignoring and not updating the output cache")
+}
+
+// withNoCache appends --no-cache to a subcommand's inner arguments if set.
+func withNoCache(args []string, noCache bool) []string {
+	if !noCache {
+		return args
+	}
+	return append(args, "--no-cache")
+}
+
+// claimSession makes way for a new orchestrator session. It refuses while
+// the latest run is still live and kills a session left over from a run that
+// has finished, e.g. one still waiting at "Press Enter to exit".
+func claimSession() error {
+	if !tmux.HasSession(sessionName) {
+		return nil
+	}
+	if r, err := run.Load(""); err == nil && r.Outcome == run.OutcomeRunning {
+		return fmt.Errorf("a swarm session is already running; use 'swarm attach' or 'swarm cancel'")
+	}
+	if err := tmux.KillSession(sessionName); err != nil {
+		return fmt.Errorf("kill stale session: %w", err)
+	}
+	return nil
+}
+
+// launchInTmux creates a tmux session running this binary as pane 0 with the
+// given arguments, then attaches. The caller must have claimed the session.
+func launchInTmux(args []string, operand string) int {
+	bin, err := os.Executable()
+	if err != nil {
+		return fail("resolve executable: %v", err)
+	}
+
+	// Create session with the orchestrator as pane 0's command
+	innerCmd := "SWARM_INSIDE=1 " + tmux.ShellEscape(bin)
+	for _, a := range append(args, "--", operand) {
+		innerCmd += " " + tmux.ShellEscape(a)
+	}
+	if err := tmux.CreateSessionWithCmd(sessionName, innerCmd); err != nil {
+		return fail("create tmux session: %v", err)
+	}
+
+	// Keep dead panes visible so user can read agent output
+	_ = tmux.ConfigureSession(sessionName)
+
+	return attachSession("choose-tree", "-w")
+}
+
+// attachSession attaches the terminal to the swarm session, optionally
+// running a tmux command (e.g. the window tree view) once attached.
+func attachSession(tmuxCmd ...string) int {
+	args := []string{"attach", "-t", sessionName}
+	if len(tmuxCmd) > 0 {
+		args = append(append(args, ";"), tmuxCmd...)
+	}
+	attach := exec.Command("tmux", args...)
+	attach.Stdin = os.Stdin
+	attach.Stdout = os.Stdout
+	attach.Stderr = os.Stderr
+	if err := attach.Run(); err != nil {
+		return fail("tmux attach: %v", err)
+	}
+	return exitOK
+}
+
+// runOrchestrator drives run r inside the tmux session. A nil graph starts
+// from scratch; otherwise orchestration resumes from the checkpointed graph.
+// With useCache, approved task outputs are memoized in .swarm/cache/.
+func runOrchestrator(r *run.Run, restored *orchestrator.Graph, mode orchestrator.PlanMode, useCache bool) int {
+	agents := []agent.Agent{
+		agent.NewArchitect(),
+		agent.NewBackend(),
+		agent.NewFrontend(),
+		agent.NewReviewer(),
+		agent.NewTester(),
+		agent.NewFixer(),
+		agent.NewDevops(),
+	}
+
+	runDir, err := filepath.Abs(r.Dir())
+	if err != nil {
+		return fail("resolve run dir: %v", err)
+	}
+	logsDir := filepath.Join(runDir, "logs")
+
+	// Everything this run reads or writes lives under its own directory.
+	artifact.BaseDir = filepath.Join(runDir, "artifacts")
+	tmux.SetPromptDir(filepath.Join(runDir, "prompts"))
+	agent.SetWorkspace(runDir, logsDir)
+
+	logFile, err := os.OpenFile(filepath.Join(logsDir, "orchestrator.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fail("open orchestrator log: %v", err)
+	}
+	defer logFile.Close()
+	log.SetOutput(io.MultiWriter(os.Stderr, logFile))
+
+	eventFile, err := os.OpenFile(r.EventsPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fail("open event log: %v", err)
+	}
+	defer eventFile.Close()
+
+	cfg, err := config.Load(config.DefaultPath)
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	orch := orchestrator.New(sessionName, agents)
+	orch.SetConfig(cfg)
+	orch.SetDurationHistory(orchestrator.RoleDurations(pastGraphs(r.ID)))
+	orch.SetEventLog(eventFile)
+	orch.SetCheckpoint(r.StatePath())
+	orch.SetPlanMode(mode)
+	orch.SetManifestDir(filepath.Join(runDir, "manifests"))
+	if useCache {
+		orch.SetCache(cache.New(cache.DefaultDir))
+	}
+	if r.Target != nil {
+		orch.SetBrownfield(r.Target.Base)
+	}
+	if restored != nil {
+		orch.Restore(restored)
+	}
+	if err := orch.StartWatching(filepath.Join(runDir, "wake")); err != nil {
+		log.Printf("file watching unavailable, polling instead: %v", err)
+	}
+	defer orch.StopWatching()
+
+	// The run has no deadline: it may wait at the plan and approval gates
+	// for as long as the user takes.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctl, err := control.Listen(r.ControlPath(), func(req control.Request) (string, error) {
+		if req.Command == control.CmdStop {
+			log.Println("stop requested: stopping launches and letting agents wrap up")
+			cancel()
+			return fmt.Sprintf("stopping run %s: agents are wrapping up", r.ID), nil
+		}
+		return orch.HandleControl(req)
+	})
+	if err != nil {
+		return fail("control socket: %v", err)
+	}
+	defer ctl.Close()
+
+	// The first Ctrl-C winds the run down and keeps its state; a second one
+	// kills the session outright.
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	go func() {
+		<-sigCh
+		log.Println("interrupted: stopping launches and letting agents wrap up (Ctrl-C again to force quit)")
+		cancel()
+		<-sigCh
+		log.Println("force quit, killing tmux session...")
+		orch.Abort()
+		if r.Target != nil {
+			commitTarget(r, run.OutcomeCancelled)
+		}
+		_ = r.Finish(run.OutcomeCancelled, fmt.Errorf("force-quit"))
+		writeSummary(r, orch.Graph())
+		_ = tmux.KillSession(sessionName)
+		os.Exit(exitError)
+	}()
+
+	log.Println("=== Claude DAG ===")
+	log.Printf("Run: %s", r.ID)
+	log.Printf("Goal: %s", r.Goal)
+
+	err = orch.Run(ctx, r.Goal)
+	signal.Stop(sigCh)
+	outcome := run.OutcomeSucceeded
+	switch {
+	case errors.Is(err, context.Canceled):
+		outcome = run.OutcomeCancelled
+	case err != nil:
+		outcome = run.OutcomeFailed
+	case mode == orchestrator.PlanOnly:
+		outcome = run.OutcomePlanned
+	}
+	if r.Target != nil {
+		commitTarget(r, outcome)
+	}
+	if ferr := r.Finish(outcome, err); ferr != nil {
+		log.Printf("save run metadata: %v", ferr)
+	}
+	writeSummary(r, orch.Graph())
+
+	switch {
+	case outcome == run.OutcomeCancelled:
+		log.Printf("swarm cancelled after %s; continue with: swarm resume %s", r.Duration(), r.ID)
+	case err != nil:
+		log.Printf("swarm failed after %s: %v", r.Duration(), err)
+	case outcome == run.OutcomePlanned:
+		log.Printf("plan ready in %s; build it with: swarm resume %s", r.Duration(), r.ID)
+	default:
+		log.Printf("swarm completed in %s", r.Duration())
+	}
+	printSummary(os.Stdout, orch.Graph(), r.ArtifactsDir())
+	fmt.Println("\nPress Enter to exit...")
+	orchestrator.WaitForEnter()
+	if err != nil {
+		return exitError
+	}
+	return exitOK
+}
+
+// commitTarget commits a brownfield run's changes to its branch and writes
+// them out as a patch set. It runs whatever the outcome, so the work of a
+// failed or cancelled run is on the branch rather than only in a worktree
+// that clean would remove; such a commit is marked as unfinished.
+func commitTarget(r *run.Run, outcome run.Outcome) {
+	patchDir, err := filepath.Abs(r.PatchesDir())
+	if err != nil {
+		log.Printf("resolve patches dir: %v", err)
+		return
+	}
+	message := "swarm: " + r.Goal
+	if outcome != run.OutcomeSucceeded {
+		message = fmt.Sprintf("swarm (%s, unfinished): %s", outcome, r.Goal)
+	}
+	patches, err := r.Target.Commit(message, patchDir)
+	if err != nil {
+		log.Printf("commit changes: %v", err)
+		return
+	}
+	if len(patches) == 0 {
+		log.Printf("no changes to %s", r.Target.Repo)
+		return
+	}
+	log.Printf("changes committed to branch %s of %s; %d patch(es) in %s", r.Target.Branch, r.Target.Repo, len(patches), patchDir)
+}
+
+// pastGraphs loads the checkpoints of every recorded run except current, so
+// the scheduler can learn how long each role usually takes.
+func pastGraphs(current string) []*orchestrator.Graph {
+	runs, err := run.List()
+	if err != nil {
+		log.Printf("load run history: %v", err)
+		return nil
+	}
+	var graphs []*orchestrator.Graph
+	for _, past := range runs {
+		if past.ID == current {
+			continue
+		}
+		g, err := orchestrator.LoadGraph(past.StatePath())
+		if err != nil {
+			continue // run never checkpointed
+		}
+		graphs = append(graphs, g)
+	}
+	return graphs
+}
+
+// preflight reports whether the external commands the swarm drives are installed.
+func preflight() bool {
+	missing := []string{}
+	for _, bin := range []string{"tmux", "claude"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			missing = append(missing, bin)
+		}
+	}
+	if len(missing) == 0 {
+		return true
+	}
+	fmt.Fprintf(os.Stderr, "required commands not found: %s\n", strings.Join(missing, ", "))
+	fmt.Fprintln(os.Stderr, "install with: sudo apt-get install tmux  (or brew install tmux)")
+	fmt.Fprintln(os.Stderr, "claude: https://docs.anthropic.com/en/docs/claude-code")
+	return false
+}
+
+// writeSummary saves the final report into the run directory: the task
+// summary with a Mermaid rendering of the graph, plus a standalone graph.dot.
+func writeSummary(r *run.Run, g *orchestrator.Graph) {
+	f, err := os.Create(r.SummaryPath())
+	if err != nil {
+		log.Printf("write summary: %v", err)
+		return
+	}
+	defer f.Close()
+	printSummary(f, g, r.ArtifactsDir())
+
+	fmt.Fprintln(f, "\n=== Task Graph ===")
+	fmt.Fprintln(f, "```mermaid")
+	orchestrator.WriteMermaid(f, g)
+	fmt.Fprintln(f, "```")
+
+	dot, err := os.Create(filepath.Join(r.Dir(), "graph.dot"))
+	if err != nil {
+		log.Printf("write graph: %v", err)
+		return
+	}
+	defer dot.Close()
+	orchestrator.WriteDOT(dot, g)
+}
+
+func printSummary(w io.Writer, g *orchestrator.Graph, artifactsDir string) {
+	fmt.Fprintln(w, "\n=== Task Summary ===")
+	for _, t := range g.Tasks() {
+		pane := ""
+		if t.PaneID != "" {
+			pane = fmt.Sprintf(" [pane %s]", t.PaneID)
+		}
+		fmt.Fprintf(w, "  [%s] %s (%s)%s: %s\n", t.Status, t.ID, t.Role, pane, t.Description)
+		orchestrator.PrintAttempts(w, t)
+	}
+	fmt.Fprintf(w, "\nArtifacts written to %s/\n", artifactsDir)
+}
@@ -2,6 +2,8 @@ package agent
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/hubenschmidt/claude-dag/internal/artifact"
 	"github.com/hubenschmidt/claude-dag/internal/model"
@@ -31,26 +33,40 @@ Do NOT write code or files anywhere else — only artifacts/contracts/.
 After writing all three files, run: touch artifacts/contracts/.done.%s
 Then STOP. Do not implement anything. Your job ends at design.`, task.Description, task.ID)
 
-	return launchInteractive(session, task.ID, system, prompt)
+	if repoMap, err := artifact.Read("repomap", "repo-map.md"); err == nil {
+		prompt += fmt.Sprintf(`
+
+--- EXISTING REPOSITORY ---
+This is a change to an existing repository, checked out read-only for you at artifacts/repo/. Design the change to fit its structure: reuse its packages, routes and data model, and only add what the goal needs. In task-plan.yaml, give every code task a "paths:" list of the repository files or directories it changes (relative to the repository root); the builder is confined to them.
+
+%s`, repoMap)
+	}
+
+	if task.Feedback != "" {
+		prompt += fmt.Sprintf("\n\n--- PREVIOUS DESIGN WAS REVIEWED ---\nRevise the existing files in artifacts/contracts/ to address this feedback:\n%s", task.Feedback)
+	}
+
+	return launchInteractive(session, task, system, prompt)
 }
 
 func (a *Architect) launchValidation(session string, task *model.Task, system string) (string, error) {
-	contractCtx, err := artifact.ReadDir("contracts")
+	ctx, err := readContext(task.ArtifactDirs...)
 	if err != nil {
-		return "", fmt.Errorf("read contracts: %w", err)
+		return "", fmt.Errorf("read artifacts: %w", err)
 	}
 
-	codeCtx, err := readCodeArtifacts()
-	if err != nil {
-		return "", fmt.Errorf("read code artifacts: %w", err)
+	// In brownfield mode the change is the worktree's diff, not a fresh tree.
+	repoNote := ""
+	if _, err := os.Stat(filepath.Join(artifact.BaseDir, artifact.RepoDir)); err == nil {
+		repoNote = `
+
+The code under artifacts/repo/ is an existing repository the agents changed. Review the change with: git -C artifacts/repo diff (and git -C artifacts/repo status for new files). Judge it against the rest of the repository as well as the contracts. Write the README.md to artifacts/ as above, not into the repository, describing how to run the changed project.`
 	}
 
 	prompt := fmt.Sprintf(`You are validating that all sub-agent implementations honor the original contracts.
 
-=== Original Contracts ===
-%s
+The original contracts (under contracts/) and the implemented code follow.
 
-=== Implemented Code ===
 %s
 
 Check for:
@@ -58,7 +74,7 @@ Check for:
 2. Data model inconsistencies between backend and frontend
 3. Missing or incompatible interfaces between components
 
-If everything is coherent, write "APPROVED" to artifacts/reviews/architect-validate.md
+If everything is coherent, write "APPROVED:" followed by a one-line summary to artifacts/reviews/architect-validate.md
 If there are issues, write "REJECTED:" followed by specific issues and which task(s) need rework to artifacts/reviews/architect-validate.md
 
 After writing the review, also write a README.md to the project root (artifacts/README.md) with:
@@ -68,22 +84,10 @@ After writing the review, also write a README.md to the project root (artifacts/
 - How to run (both backend and frontend if applicable)
 - Example usage (curl commands, URLs to visit, etc.)
 
-Keep it concise and practical — just enough for someone to clone and run.
+Keep it concise and practical — just enough for someone to clone and run.%s
 
 After writing both files, run: touch artifacts/reviews/.done.%s
-Then STOP.`, contractCtx, codeCtx, task.ID)
+Then STOP.`, ctx, repoNote, task.ID)
 
-	return launchInteractive(session, task.ID, system, prompt)
-}
-
-func readCodeArtifacts() (string, error) {
-	var result string
-	for _, dir := range []string{"code/backend", "code/frontend", "schemas"} {
-		content, err := artifact.ReadDir(dir)
-		if err != nil {
-			continue // dir may not exist if no agent produced it
-		}
-		result += content
-	}
-	return result, nil
+	return launchInteractive(session, task, system, prompt)
 }
@@ -3,7 +3,6 @@ package agent
 import (
 	"fmt"
 
-	"github.com/hubenschmidt/claude-dag/internal/artifact"
 	"github.com/hubenschmidt/claude-dag/internal/model"
 )
 
@@ -19,11 +18,18 @@ func (b *Backend) Launch(session string, task *model.Task) (string, error) {
 		return "", err
 	}
 
-	contractCtx, err := artifact.ReadDir("contracts")
+	contractCtx, err := readContext("contracts")
 	if err != nil {
 		return "", fmt.Errorf("read contracts: %w", err)
 	}
 
+	location := fmt.Sprintf(`Write all code files to artifacts/code/backend/ directory ONLY. Do NOT modify go.mod, go.sum, or any file outside artifacts/.
+When completely finished, run: touch artifacts/code/backend/.done.%s
+Then STOP.`, task.ID)
+	if inRepo(task) {
+		location = repoInstructions(task)
+	}
+
 	prompt := fmt.Sprintf(`Task: %s
 
 Architect artifacts:
@@ -32,13 +38,10 @@ Architect artifacts:
 Before making interface decisions, check artifacts/shared-context/ for decisions from other agents.
 Write your own key decisions (endpoint signatures, data shapes) to artifacts/shared-context/.
 
-Write all code files to artifacts/code/backend/ directory ONLY. Do NOT modify go.mod, go.sum, or any file outside artifacts/.
-When completely finished, run: touch artifacts/code/backend/.done.%s
-Then STOP.`, task.Description, contractCtx, task.ID)
+%s`, task.Description, contractCtx, location)
 
-	if task.Feedback != "" {
-		prompt += fmt.Sprintf("\n\n--- PREVIOUS ATTEMPT WAS REJECTED ---\nAttempt %d/%d. Reviewer feedback:\n%s\n\nFix the issues listed above.", task.Attempts+1, model.MaxAttempts, task.Feedback)
-	}
+	prompt += subPlanSection(task)
+	prompt += reworkSection(task)
 
-	return launchInteractive(session, task.ID, system, prompt)
+	return launchInteractive(session, task, system, prompt)
 }
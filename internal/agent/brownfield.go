@@ -0,0 +1,30 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hubenschmidt/claude-dag/internal/artifact"
+	"github.com/hubenschmidt/claude-dag/internal/model"
+)
+
+// inRepo reports whether a builder task changes the target repository rather
+// than writing a fresh tree under artifacts/code/.
+func inRepo(task *model.Task) bool {
+	return task.OutputDir == artifact.RepoDir
+}
+
+// repoInstructions replaces a builder's greenfield location rules when it
+// works on an existing repository checked out at artifacts/repo/.
+func repoInstructions(task *model.Task) string {
+	scope := "Find the code to change yourself before editing."
+	if len(task.Paths) > 0 {
+		scope = "Confine your changes to these paths: " + strings.Join(task.Paths, ", ") + "."
+	}
+	return fmt.Sprintf(`You are changing an EXISTING repository checked out at artifacts/repo/. %s
+Read the surrounding code first and follow its conventions: layout, naming, error handling, test style.
+Edit existing files in place; add new files only where the repository would put them. Do NOT commit — the swarm commits your changes.
+Do NOT modify any file outside artifacts/.
+When completely finished, run: touch artifacts/%s/.done.%s
+Then STOP.`, scope, task.OutputDir, task.ID)
+}
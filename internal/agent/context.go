@@ -0,0 +1,13 @@
+package agent
+
+import "github.com/hubenschmidt/claude-dag/internal/artifact"
+
+// contextBudget is the token budget for the artifacts quoted in a prompt,
+// leaving the rest of the model's window for the agent's own work.
+const contextBudget = 60000
+
+// readContext renders the files of dirs, most relevant first, within the
+// context budget.
+func readContext(dirs ...string) (string, error) {
+	return artifact.NewContext(contextBudget).Add(dirs...).Build()
+}
@@ -0,0 +1,41 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/hubenschmidt/claude-dag/internal/model"
+)
+
+type Devops struct{}
+
+func NewDevops() *Devops { return &Devops{} }
+
+func (d *Devops) Role() model.AgentRole { return model.RoleDevops }
+
+func (d *Devops) Launch(session string, task *model.Task) (string, error) {
+	system, err := LoadPrompt("devops")
+	if err != nil {
+		return "", err
+	}
+
+	ctx, err := readContext(task.ArtifactDirs...)
+	if err != nil {
+		return "", fmt.Errorf("read artifacts: %w", err)
+	}
+
+	prompt := fmt.Sprintf(`Task: %s
+
+The contracts and the code to deploy follow.
+
+%s
+
+Write all files to artifacts/%s/ ONLY: Dockerfiles, compose.yaml, a Makefile and .env.example.
+Reference code with paths relative to artifacts/%s/ (e.g. build context ../code/backend); every path must exist.
+Do NOT modify any file outside artifacts/%s/.
+When completely finished, run: touch artifacts/%s/.done.%s
+Then STOP.`, task.Description, ctx, task.OutputDir, task.OutputDir, task.OutputDir, task.OutputDir, task.ID)
+
+	prompt += reworkSection(task)
+
+	return launchInteractive(session, task, system, prompt)
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hubenschmidt/claude-dag/internal/artifact"
+	"github.com/hubenschmidt/claude-dag/internal/model"
+)
+
+// FormatFeedbackRounds renders feedback rounds oldest first, one headed
+// section per round.
+func FormatFeedbackRounds(rounds []model.FeedbackRound) string {
+	var b strings.Builder
+	for i, r := range rounds {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		if r.By == "user" {
+			fmt.Fprintf(&b, "=== Round %d: guidance from the user ===\n", i+1)
+		} else {
+			fmt.Fprintf(&b, "=== Round %d: attempt %d, rejected by %s ===\n", i+1, r.Attempt, r.By)
+		}
+		b.WriteString(strings.TrimSpace(r.Feedback))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// reworkSection tells a builder about every earlier round of feedback on its
+// task, so fixing the latest issues doesn't bring back older ones. It is empty
+// on a first attempt.
+func reworkSection(task *model.Task) string {
+	rounds := task.FeedbackRounds()
+	if len(rounds) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(`
+
+--- PREVIOUS ATTEMPTS WERE REJECTED ---
+Attempt %d, after %d of %d allowed rejections. Feedback from every earlier round follows, oldest first. Fix the latest issues without reintroducing any raised in earlier rounds.
+
+%s
+Fix the issues listed above.`, len(task.History), task.Attempts, task.RetryPolicy().MaxAttempts, FormatFeedbackRounds(rounds))
+}
+
+// earlierRoundsSection gives a reviewer the issues raised about the reviewed
+// task in earlier rounds, read from artifacts/feedback/. It is empty on a
+// first review.
+func earlierRoundsSection(reviewedID string) string {
+	rounds, err := artifact.Read("feedback", reviewedID+".md")
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf(`
+
+--- EARLIER REVIEW ROUNDS ---
+This code was sent back before. Check that every issue below is still fixed, and reject it if any has come back.
+
+%s`, rounds)
+}
@@ -0,0 +1,53 @@
+package agent
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hubenschmidt/claude-dag/internal/artifact"
+	"github.com/hubenschmidt/claude-dag/internal/model"
+)
+
+type Fixer struct{}
+
+func NewFixer() *Fixer { return &Fixer{} }
+
+func (f *Fixer) Role() model.AgentRole { return model.RoleFixer }
+
+// Launch starts a fixer with nothing but the failing verification output
+// (task.Feedback, refreshed after each failed fix) and the files it may edit.
+func (f *Fixer) Launch(session string, task *model.Task) (string, error) {
+	system, err := LoadPrompt("fixer")
+	if err != nil {
+		return "", err
+	}
+
+	var files strings.Builder
+	var paths []string
+	for _, name := range task.Files {
+		path := filepath.Join("artifacts", task.OutputDir, name)
+		paths = append(paths, path)
+		content, err := artifact.Read(task.OutputDir, name)
+		if err != nil {
+			return "", &PromptError{fmt.Errorf("read %s: %w", path, err)}
+		}
+		fmt.Fprintf(&files, "=== %s ===\n%s\n", path, content)
+	}
+
+	prompt := fmt.Sprintf(`The output of task %s failed verification. Make the command below pass.
+
+You may edit ONLY these files:
+%s
+
+Do NOT create, delete or edit any other file.
+When you are done, run: touch artifacts/%s/.done.%s
+Then STOP.
+
+=== Failing output ===
+%s
+
+%s`, task.FixTaskID, strings.Join(paths, "\n"), task.OutputDir, task.ID, task.Feedback, files.String())
+
+	return launchInteractive(session, task, system, prompt)
+}
@@ -1,43 +1,176 @@
 package agent
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/hubenschmidt/claude-dag/internal/model"
 	"github.com/hubenschmidt/claude-dag/internal/tmux"
+	"github.com/hubenschmidt/claude-dag/prompts"
 )
 
-const defaultPromptDir = "prompts"
+// projectPromptDir is the per-project override directory, relative to the
+// working directory.
+const projectPromptDir = ".swarm/prompts"
 
-// LoadPrompt reads a prompt markdown file from the prompts directory.
+// promptDirs lists override directories searched before the embedded
+// defaults, highest priority first.
+var promptDirs = DefaultPromptDirs("")
+
+// workDir is the directory agent windows start in; relative "artifacts/..."
+// paths in prompts resolve against it. Empty means the session default.
+var workDir string
+
+// logDir receives a transcript of every agent pane when non-empty.
+var logDir string
+
+// SetWorkspace sets the working directory for agent windows and the directory
+// their pane transcripts are written to.
+func SetWorkspace(work, logs string) {
+	workDir = work
+	logDir = logs
+}
+
+// PromptSource describes where a role's prompt was resolved from.
+type PromptSource struct {
+	Name     string
+	Path     string // override file path, or "embedded:<name>.md"
+	Embedded bool
+}
+
+// DefaultPromptDirs returns the override search path: the explicit flag
+// directory (if any), the project .swarm/prompts/, then the user config dir.
+func DefaultPromptDirs(flagDir string) []string {
+	var dirs []string
+	if flagDir != "" {
+		dirs = append(dirs, flagDir)
+	}
+	dirs = append(dirs, projectPromptDir)
+	if cfg, err := os.UserConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(cfg, "claude-dag", "prompts"))
+	}
+	return dirs
+}
+
+// SetPromptDirs replaces the override search path.
+func SetPromptDirs(dirs []string) {
+	promptDirs = dirs
+}
+
+// PromptError is a launch failure in building an agent's prompt, such as a
+// missing prompt file or an unreadable input. It fails the same way every
+// time, so relaunching the agent won't help.
+type PromptError struct {
+	Err error
+}
+
+func (e *PromptError) Error() string { return e.Err.Error() }
+func (e *PromptError) Unwrap() error { return e.Err }
+
+// IsPromptError reports whether err, a launch error, is a PromptError.
+func IsPromptError(err error) bool {
+	var pe *PromptError
+	return errors.As(err, &pe)
+}
+
+// LoadPrompt returns the prompt for name, preferring the first override file
+// found on the search path and falling back to the embedded default.
 func LoadPrompt(name string) (string, error) {
-	path := filepath.Join(defaultPromptDir, name+".md")
-	data, err := os.ReadFile(path)
+	content, _, err := resolvePrompt(name)
+	return content, err
+}
+
+// ResolvePrompt reports which file LoadPrompt would use for name.
+func ResolvePrompt(name string) (PromptSource, error) {
+	_, src, err := resolvePrompt(name)
+	return src, err
+}
+
+func resolvePrompt(name string) (string, PromptSource, error) {
+	file := name + ".md"
+	for _, dir := range promptDirs {
+		path := filepath.Join(dir, file)
+		data, err := os.ReadFile(path)
+		if err == nil {
+			return string(data), PromptSource{Name: name, Path: path}, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", PromptSource{}, &PromptError{fmt.Errorf("load prompt %s: %w", name, err)}
+		}
+	}
+
+	data, err := prompts.FS.ReadFile(file)
 	if err != nil {
-		return "", fmt.Errorf("load prompt %s: %w", name, err)
+		return "", PromptSource{}, &PromptError{fmt.Errorf("load prompt %s: %w", name, err)}
 	}
-	return string(data), nil
+	return string(data), PromptSource{Name: name, Path: "embedded:" + file, Embedded: true}, nil
 }
 
-// launchInteractive writes the prompt to a temp file, launches claude in
-// interactive mode in a named tmux window, then sends a short "read that
-// file" instruction via SendKeys to auto-submit while keeping the full TUI.
-func launchInteractive(session, taskID, systemPrompt, promptText string) (string, error) {
-	promptPath, err := tmux.WritePromptFile(taskID, promptText)
+// ListPrompts resolves every known prompt name — embedded defaults plus any
+// extra names found in override directories — in sorted order.
+func ListPrompts() ([]PromptSource, error) {
+	names := map[string]bool{}
+	embedded, err := fs.Glob(prompts.FS, "*.md")
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range embedded {
+		names[strings.TrimSuffix(f, ".md")] = true
+	}
+	for _, dir := range promptDirs {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.md"))
+		for _, m := range matches {
+			names[strings.TrimSuffix(filepath.Base(m), ".md")] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(names))
+	for n := range names {
+		sorted = append(sorted, n)
+	}
+	sort.Strings(sorted)
+
+	sources := make([]PromptSource, 0, len(sorted))
+	for _, n := range sorted {
+		src, err := ResolvePrompt(n)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, src)
+	}
+	return sources, nil
+}
+
+// launchInteractive writes the prompt to the current attempt's prompt file
+// (see tmux.PromptPath) and launches claude in interactive mode in a named
+// tmux window. It then types a short "read that file" instruction to
+// auto-submit, keeping the full TUI.
+func launchInteractive(session string, task *model.Task, systemPrompt, promptText string) (string, error) {
+	taskID := task.ID
+	promptPath, err := tmux.WritePromptFile(taskID, len(task.History), promptText)
 	if err != nil {
 		return "", err
 	}
 
-	escaped := shellEscape(systemPrompt)
+	escaped := tmux.ShellEscape(systemPrompt)
 	cmd := fmt.Sprintf("claude --append-system-prompt %s --allowedTools Edit Read Write Bash Glob Grep", escaped)
 	initialMsg := fmt.Sprintf("Read and follow all instructions in %s", promptPath)
 
-	return tmux.NewAutoWindow(session, taskID, cmd, initialMsg)
-}
+	paneID, err := tmux.NewAutoWindow(session, taskID, workDir, cmd, initialMsg)
+	if err != nil {
+		return "", err
+	}
 
-// shellEscape wraps a string in single quotes, escaping internal single quotes.
-func shellEscape(s string) string {
-	return "'" + strings.ReplaceAll(s, "'", "'\"'\"'") + "'"
+	if logDir != "" {
+		if err := tmux.PipePane(paneID, filepath.Join(logDir, taskID+".log")); err != nil {
+			log.Printf("[agent] pane log for %s: %v", taskID, err)
+		}
+	}
+	return paneID, nil
 }
@@ -19,8 +19,15 @@ func (r *Reviewer) Launch(session string, task *model.Task) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if task.Lens != "" {
+		lens, err := LoadPrompt("review-" + task.Lens)
+		if err != nil {
+			return "", err
+		}
+		system += "\n\n" + lens + panelNote(task.Lens, task.Lenses)
+	}
 
-	codeCtx, err := buildReviewContext(task.ArtifactDirs)
+	codeCtx, err := reviewContext(task)
 	if err != nil {
 		return "", fmt.Errorf("build review context: %w", err)
 	}
@@ -31,17 +38,54 @@ Then STOP.
 
 %s`, task.ID, task.ID, codeCtx)
 
-	return launchInteractive(session, task.ID, system, prompt)
+	if task.ReviewTaskID != "" {
+		prompt += earlierRoundsSection(task.ReviewTaskID)
+	}
+
+	return launchInteractive(session, task, system, prompt)
 }
 
-func buildReviewContext(dirs []string) (string, error) {
-	var b strings.Builder
-	for _, dir := range dirs {
-		content, err := artifact.ReadDir(dir)
-		if err != nil {
-			return "", err
+// panelNote tells a panel reviewer which lenses the rest of its panel
+// covers, so it leaves those to them.
+func panelNote(lens string, lenses []string) string {
+	var others []string
+	for _, l := range lenses {
+		if l != lens {
+			others = append(others, l)
+		}
+	}
+	switch len(others) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf("\n\nYou are one reviewer on a panel. Another reviewer covers the %s lens; leave it to them and stick to yours.", others[0])
+	}
+	return fmt.Sprintf("\n\nYou are one reviewer on a panel. Other reviewers cover the %s and %s lenses; leave those to them and stick to yours.",
+		strings.Join(others[:len(others)-1], ", "), others[len(others)-1])
+}
+
+// reviewContext shows a reviewer the files its task wrote, and the contracts,
+// when the task's manifests say which those are; otherwise everything in the
+// reviewer's artifact dirs.
+func reviewContext(task *model.Task) (string, error) {
+	if len(task.Changed) == 0 {
+		return readContext(task.ArtifactDirs...)
+	}
+	ctx, err := artifact.NewContext(contextBudget).AddFiles(task.Changed...).Add("contracts").Build()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("These are the files task %s wrote. Review them; other files in %s are not part of this change, so read them only for context.\n\n%s",
+		task.ReviewTaskID, dirList(task.ArtifactDirs), ctx), nil
+}
+
+// dirList names artifact dirs for a prompt, e.g. "artifacts/code/backend/".
+func dirList(dirs []string) string {
+	names := make([]string, 0, len(dirs))
+	for _, d := range dirs {
+		if d != "contracts" {
+			names = append(names, "artifacts/"+d+"/")
 		}
-		b.WriteString(content)
 	}
-	return b.String(), nil
+	return strings.Join(names, ", ")
 }
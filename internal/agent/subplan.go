@@ -0,0 +1,16 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/hubenschmidt/claude-dag/internal/model"
+)
+
+// subPlanSection tells a builder how to split its task when it turns out to
+// be too big for one agent.
+func subPlanSection(task *model.Task) string {
+	return fmt.Sprintf(`
+
+--- SPLITTING THIS TASK ---
+If this task is too big for one agent (e.g. it should really be several services), do the shared groundwork yourself and write a sub-plan to artifacts/plans/%s.yaml, in the same schema as artifacts/contracts/task-plan.yaml (id, role, description, depends_on). Each entry becomes a task run after yours; entries without depends_on depend on this task, and tasks waiting on this one will wait on them too. Use new, unique IDs. Write the sub-plan before touching your .done file.`, task.ID)
+}
@@ -0,0 +1,57 @@
+package agent
+
+import (
+	"fmt"
+
+	"github.com/hubenschmidt/claude-dag/internal/model"
+)
+
+type Tester struct{}
+
+func NewTester() *Tester { return &Tester{} }
+
+func (t *Tester) Role() model.AgentRole { return model.RoleTester }
+
+// Launch starts a tester for the code task named by task.TestTaskID. The
+// tested task's output dir comes first in task.ArtifactDirs; the tests are
+// written into it, next to the code they exercise.
+func (t *Tester) Launch(session string, task *model.Task) (string, error) {
+	system, err := LoadPrompt("tester")
+	if err != nil {
+		return "", err
+	}
+
+	if len(task.ArtifactDirs) == 0 {
+		return "", &PromptError{fmt.Errorf("tester %s has no code dir to test", task.ID)}
+	}
+	codeDir := task.ArtifactDirs[0]
+	codeCtx, err := readContext(task.ArtifactDirs...)
+	if err != nil {
+		return "", fmt.Errorf("build test context: %w", err)
+	}
+
+	prompt := fmt.Sprintf(`Derive tests for the code produced by task %s from artifacts/contracts/api-contract.yaml and artifacts/contracts/data-model.yaml.
+Write the test files into artifacts/%s/ ONLY, next to the code they test, and name them after task %s (e.g. %s_test.go).
+Do NOT modify any non-test file. Run the tests from artifacts/%s/.
+Write your report to artifacts/tests/%s.md.
+When completely finished, run: touch artifacts/tests/.done.%s
+Then STOP.
+
+%s`, task.TestTaskID, codeDir, task.TestTaskID, testFileStem(task.TestTaskID), codeDir, task.ID, task.ID, codeCtx)
+
+	prompt += earlierRoundsSection(task.TestTaskID)
+
+	return launchInteractive(session, task, system, prompt)
+}
+
+// testFileStem turns a task ID into a file name stem valid in every language
+// the swarm generates.
+func testFileStem(taskID string) string {
+	stem := []rune(taskID)
+	for i, r := range stem {
+		if r == '-' || r == '.' {
+			stem[i] = '_'
+		}
+	}
+	return string(stem)
+}
@@ -1,13 +1,24 @@
 package artifact
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"unicode/utf8"
 )
 
-const BaseDir = "artifacts"
+// BaseDir is the artifacts root. It defaults to ./artifacts and is pointed at
+// the current run's directory by the orchestrator process.
+var BaseDir = "artifacts"
+
+// RepoDir is the subdir of BaseDir holding the worktree of the target
+// repository in brownfield mode.
+const RepoDir = "repo"
 
 func Write(subdir, filename, content string) error {
 	dir := filepath.Join(BaseDir, subdir)
@@ -27,36 +38,105 @@ func Read(subdir, filename string) (string, error) {
 	return string(data), nil
 }
 
-func ReadAll(subdir string) (map[string]string, error) {
+// MaxFileSize is the most of one file ReadAll returns; the rest is cut off.
+const MaxFileSize = 128 << 10
+
+// File is a text file read from an artifact dir.
+type File struct {
+	Path      string // relative to the dir read, slash-separated
+	Content   string
+	Size      int64 // on disk, which may exceed len(Content)
+	Truncated bool  // Content is the first MaxFileSize bytes only
+}
+
+// ReadAll reads every text file under subdir, recursively, sorted by path.
+// Sentinels, binary files, dependency and build output dirs and anything
+// excluded by a .gitignore in or above subdir are skipped.
+func ReadAll(subdir string) ([]File, error) {
 	dir := filepath.Join(BaseDir, subdir)
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return nil, fmt.Errorf("readdir %s: %w", dir, err)
+	var rules ignoreRules
+	for _, a := range ancestors(subdir) {
+		rules.load(a)
 	}
 
-	result := make(map[string]string, len(entries))
-	for _, e := range entries {
-		if e.IsDir() {
-			continue
+	var files []File
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(BaseDir, path)
+		if d.IsDir() {
+			if path == dir {
+				rules.load(rel)
+				return nil
+			}
+			if ignoredDirs[d.Name()] || rules.ignored(rel, true) {
+				return filepath.SkipDir
+			}
+			rules.load(rel)
+			return nil
+		}
+		if !d.Type().IsRegular() || strings.HasPrefix(d.Name(), ".done") || d.Name() == ".git" || rules.ignored(rel, false) {
+			return nil
 		}
-		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		f, err := readText(path)
 		if err != nil {
-			return nil, fmt.Errorf("read %s: %w", e.Name(), err)
+			return err
 		}
-		result[e.Name()] = string(data)
+		if f == nil {
+			return nil // binary
+		}
+		name, _ := filepath.Rel(dir, path)
+		f.Path = filepath.ToSlash(name)
+		files = append(files, *f)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", dir, err)
 	}
-	return result, nil
+	slices.SortFunc(files, func(a, b File) int { return strings.Compare(a.Path, b.Path) })
+	return files, nil
 }
 
-func ReadDir(subdir string) (string, error) {
-	files, err := ReadAll(subdir)
+// readText reads up to MaxFileSize bytes of a file, returning nil if it looks
+// binary: a NUL byte or invalid UTF-8 in what was read.
+func readText(path string) (*File, error) {
+	fh, err := os.Open(path)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	defer fh.Close()
+	info, err := fh.Stat()
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(fh, MaxFileSize))
+	if err != nil {
+		return nil, err
+	}
+	f := &File{Size: info.Size(), Truncated: info.Size() > MaxFileSize}
+	if f.Truncated {
+		data = trimPartial(data)
+	}
+	if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+		return nil, nil
+	}
+	f.Content = string(data)
+	return f, nil
+}
 
-	var b strings.Builder
-	for name, content := range files {
-		fmt.Fprintf(&b, "=== %s ===\n%s\n\n", name, content)
+// trimPartial cuts the end off data read from a longer file: back to the
+// last full line, or with no line break, to the last full rune, so a
+// multi-byte rune split by the cut doesn't make the text look binary.
+func trimPartial(data []byte) []byte {
+	if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+		return data[:i+1]
+	}
+	for n := 1; n < utf8.UTFMax && len(data) > 0; n++ {
+		if r, size := utf8.DecodeLastRune(data); r != utf8.RuneError || size > 1 {
+			break
+		}
+		data = data[:len(data)-1]
 	}
-	return b.String(), nil
+	return data
 }
@@ -0,0 +1,354 @@
+package artifact
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSummaryLines caps the signatures listed for one summarized file.
+const maxSummaryLines = 40
+
+// headerShare is the fraction of the budget, 1/headerShare, the file list
+// may take before it is collapsed to counts per directory.
+const headerShare = 4
+
+// maxOmittedNamed caps the files named as not shown at all.
+const maxOmittedNamed = 20
+
+// lockFiles are generated and large, and never worth their tokens.
+var lockFiles = map[string]bool{
+	"go.sum": true, "package-lock.json": true, "yarn.lock": true,
+	"pnpm-lock.yaml": true, "Cargo.lock": true, "poetry.lock": true,
+}
+
+// entrypoints are file stems that usually show how a component fits together.
+var entrypoints = map[string]bool{
+	"main": true, "index": true, "app": true, "server": true,
+	"routes": true, "router": true, "handlers": true,
+}
+
+var (
+	goSignature  = regexp.MustCompile(`^(package|func|type|var|const)\b`)
+	jsSignature  = regexp.MustCompile(`^(export\s+)?(default\s+)?(async\s+)?(function|class|interface|type|const|enum)\b`)
+	pySignature  = regexp.MustCompile(`^\s*(async\s+)?(def|class)\s`)
+	yamlKey      = regexp.MustCompile(`^( {0,2})[\w"'./{}-]+:`)
+	sqlStatement = regexp.MustCompile(`(?i)^\s*(create|alter)\s`)
+)
+
+// Context assembles the files of artifact dirs into prompt context that fits
+// a token budget. It always lists every file, or when that list alone would
+// crowd out the files, counts them per directory; the most relevant files
+// are included in full, the rest as signatures, and whatever still doesn't
+// fit is named as omitted so the agent knows to read it itself.
+//
+// Relevance is, in order: the order dirs and files were added in, the kind
+// of file (contracts and docs, then entrypoints, other source, tests)
+// and finally the path, so the same inputs always give the same context.
+type Context struct {
+	budget  int
+	sources []source
+}
+
+// source is an artifact dir or a single file, relative to BaseDir.
+type source struct {
+	path string
+	file bool
+}
+
+// NewContext returns an empty context limited to budget tokens; zero means
+// no limit.
+func NewContext(budget int) *Context {
+	return &Context{budget: budget}
+}
+
+// Add appends artifact dirs, most relevant first. Dirs that don't exist are
+// skipped when the context is built.
+func (c *Context) Add(subdirs ...string) *Context {
+	for _, d := range subdirs {
+		c.sources = append(c.sources, source{path: d})
+	}
+	return c
+}
+
+// AddFiles appends single files, given relative to BaseDir, ranked like a
+// dir added at this point. Missing and binary files are skipped.
+func (c *Context) AddFiles(paths ...string) *Context {
+	for _, p := range paths {
+		c.sources = append(c.sources, source{path: p, file: true})
+	}
+	return c
+}
+
+type contextFile struct {
+	path      string // relative to BaseDir, slash-separated
+	content   string
+	truncated bool
+	dir       int // index of the source it came from
+}
+
+// Build reads the dirs and renders the context.
+func (c *Context) Build() (string, error) {
+	var files []contextFile
+	seen := map[string]bool{}
+	for i, src := range c.sources {
+		read, err := src.read()
+		if errors.Is(err, fs.ErrNotExist) {
+			continue // no agent produced it
+		}
+		if err != nil {
+			return "", err
+		}
+		for _, f := range read {
+			p := path.Join(filepath.ToSlash(src.path), f.Path)
+			if seen[p] {
+				continue // dir added twice, or nested in another
+			}
+			seen[p] = true
+			files = append(files, contextFile{path: p, content: f.Content, truncated: f.Truncated, dir: i})
+		}
+	}
+	slices.SortFunc(files, c.compare)
+
+	header := c.header(files)
+	full, summaries, partial, omitted := c.fit(files, c.budget-tokens(header))
+	if len(partial) > 0 && c.budget != 0 {
+		// The note naming what's left out counts against the budget too:
+		// fit again with room for it at its longest.
+		names := make([]string, len(files))
+		for i, f := range files {
+			names[i] = f.path
+		}
+		slices.SortFunc(names, func(a, b string) int { return len(b) - len(a) })
+		reserve := tokens(leftOutNote(len(files), len(files), 0, names))
+		full, summaries, partial, omitted = c.fit(files, c.budget-tokens(header)-reserve)
+	}
+
+	var b strings.Builder
+	b.WriteString(header)
+	b.WriteString(full)
+	b.WriteString(summaries)
+	if len(partial) > 0 {
+		b.WriteString(leftOutNote(len(files), len(partial), len(partial)-len(omitted), omitted))
+	}
+	return b.String(), nil
+}
+
+// fit renders as many files in full as remaining tokens allow, in order, and
+// as many of the rest as signatures. It returns the files not shown in full,
+// and the paths of those not shown at all. A zero budget fits everything.
+func (c *Context) fit(files []contextFile, remaining int) (full, summaries string, partial []contextFile, omitted []string) {
+	fits := func(s string) bool {
+		if c.budget == 0 {
+			return true
+		}
+		if tokens(s) > remaining {
+			return false
+		}
+		remaining -= tokens(s)
+		return true
+	}
+
+	var fb, sb strings.Builder
+	for _, f := range files {
+		section := fmt.Sprintf("=== %s ===\n%s\n\n", f.path, f.content)
+		if lockFiles[path.Base(f.path)] || !fits(section) {
+			partial = append(partial, f)
+			continue
+		}
+		fb.WriteString(section)
+	}
+	for _, f := range partial {
+		sig := summarize(f.path, f.content)
+		section := fmt.Sprintf("=== %s (signatures only) ===\n%s\n\n", f.path, sig)
+		if sig == "" || !fits(section) {
+			omitted = append(omitted, f.path)
+			continue
+		}
+		sb.WriteString(section)
+	}
+	return fb.String(), sb.String(), partial, omitted
+}
+
+// leftOutNote tells the agent which files it wasn't shown in full, naming
+// at most maxOmittedNamed of those it wasn't shown at all.
+func leftOutNote(total, left, summarized int, omitted []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "=== Not shown in full ===\n%d of %d files are left out to fit the context budget: %d shown as signatures only", left, total, summarized)
+	if n := len(omitted); n > maxOmittedNamed {
+		fmt.Fprintf(&b, ", and %s and %d more not at all", strings.Join(omitted[:maxOmittedNamed], ", "), n-maxOmittedNamed)
+	} else if n > 0 {
+		fmt.Fprintf(&b, ", and %s not at all", strings.Join(omitted, ", "))
+	}
+	b.WriteString(". Read them from artifacts/<path> yourself before relying on their contents.\n")
+	return b.String()
+}
+
+// header lists every file with its size. A list that would take more than
+// 1/headerShare of the budget is collapsed to file counts per directory, and
+// if that is still too long, per source.
+func (c *Context) header(files []contextFile) string {
+	total := 0
+	for _, f := range files {
+		total += tokens(f.content)
+	}
+	groupings := []func(contextFile) string{
+		nil,
+		func(f contextFile) string { return path.Dir(f.path) + "/" },
+		func(f contextFile) string {
+			if src := c.sources[f.dir]; !src.file {
+				return filepath.ToSlash(src.path) + "/"
+			}
+			return path.Dir(f.path) + "/"
+		},
+	}
+	var header string
+	for _, group := range groupings {
+		header = fmt.Sprintf("=== Files (%d, ~%d tokens) ===\n%s\n", len(files), total, listFiles(files, group))
+		if c.budget == 0 || tokens(header)*headerShare <= c.budget {
+			break
+		}
+	}
+	return header
+}
+
+// listFiles lists files one per line, or if group is set, one line per
+// group with its file count, in order of each group's first file.
+func listFiles(files []contextFile, group func(contextFile) string) string {
+	if len(files) == 0 {
+		return "(none)\n"
+	}
+	var b strings.Builder
+	if group == nil {
+		for _, f := range files {
+			note := ""
+			if f.truncated {
+				note = ", truncated"
+			}
+			fmt.Fprintf(&b, "%s (~%d tokens%s)\n", f.path, tokens(f.content), note)
+		}
+		return b.String()
+	}
+
+	var order []string
+	count, size := map[string]int{}, map[string]int{}
+	for _, f := range files {
+		g := group(f)
+		if count[g] == 0 {
+			order = append(order, g)
+		}
+		count[g]++
+		size[g] += tokens(f.content)
+	}
+	for _, g := range order {
+		fmt.Fprintf(&b, "%s (%d file(s), ~%d tokens)\n", g, count[g], size[g])
+	}
+	return b.String()
+}
+
+// read returns the text files of a source, with paths relative to it.
+func (s source) read() ([]File, error) {
+	if !s.file {
+		return ReadAll(s.path)
+	}
+	f, err := readText(filepath.Join(BaseDir, s.path))
+	if err != nil || f == nil {
+		return nil, err
+	}
+	return []File{*f}, nil // Path "" joins to the source path
+}
+
+func (c *Context) compare(a, b contextFile) int {
+	if a.dir != b.dir {
+		return a.dir - b.dir
+	}
+	if ka, kb := kindRank(a.path), kindRank(b.path); ka != kb {
+		return ka - kb
+	}
+	return strings.Compare(a.path, b.path)
+}
+
+// kindRank orders files by how much they tell an agent about the rest:
+// contracts and docs, entrypoints, other source, tests, lock files.
+func kindRank(p string) int {
+	name := path.Base(p)
+	ext := path.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	switch {
+	case lockFiles[name]:
+		return 4
+	case strings.HasSuffix(stem, "_test") || strings.Contains(name, ".test.") || strings.Contains(name, ".spec."):
+		return 3
+	case ext == ".yaml" || ext == ".yml" || ext == ".md" || ext == ".sql" || ext == ".proto" || ext == ".graphql" || ext == ".json":
+		return 0
+	case entrypoints[stem]:
+		return 1
+	}
+	return 2
+}
+
+// tokens estimates the token count of s at four bytes per token.
+func tokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// summarize returns the signature lines of a file: declarations for source,
+// top-level keys for YAML, statements for SQL, else the first line. Lock
+// files have none.
+func summarize(p, content string) string {
+	if lockFiles[path.Base(p)] {
+		return ""
+	}
+	var match func(string) bool
+	switch path.Ext(p) {
+	case ".go":
+		match = goSignature.MatchString
+	case ".ts", ".tsx", ".js", ".jsx", ".mjs":
+		match = jsSignature.MatchString
+	case ".py":
+		match = pySignature.MatchString
+	case ".yaml", ".yml":
+		match = yamlKey.MatchString
+	case ".sql":
+		match = sqlStatement.MatchString
+	}
+
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			continue
+		}
+		if match == nil {
+			return truncate(line)
+		}
+		if !match(line) {
+			continue
+		}
+		if len(lines) == maxSummaryLines {
+			lines = append(lines, "...")
+			break
+		}
+		lines = append(lines, truncate(strings.TrimSuffix(line, " {")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncate shortens a signature line to at most 200 bytes, cutting on a rune
+// boundary so the prompt stays valid UTF-8.
+func truncate(line string) string {
+	if len(line) <= 200 {
+		return line
+	}
+	cut := 200
+	for cut > 0 && !utf8.RuneStart(line[cut]) {
+		cut--
+	}
+	return line[:cut] + "..."
+}
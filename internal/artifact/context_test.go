@@ -0,0 +1,219 @@
+package artifact
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// useBaseDir points BaseDir at a fresh temp dir holding files, given by
+// slash-separated path, for the length of the test.
+func useBaseDir(t *testing.T, files map[string]string) {
+	t.Helper()
+	old := BaseDir
+	BaseDir = t.TempDir()
+	t.Cleanup(func() { BaseDir = old })
+	for name, content := range files {
+		path := filepath.Join(BaseDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// sections returns the "=== ... ===" headings of a built context, in order.
+func sections(ctx string) []string {
+	var heads []string
+	for _, line := range strings.Split(ctx, "\n") {
+		if strings.HasPrefix(line, "=== ") && strings.HasSuffix(line, " ===") {
+			heads = append(heads, strings.TrimSuffix(strings.TrimPrefix(line, "=== "), " ==="))
+		}
+	}
+	return heads
+}
+
+func TestBuildUnlimited(t *testing.T) {
+	useBaseDir(t, map[string]string{
+		"backend/main.go":         "package main\n",
+		"backend/store.go":        "package main\n",
+		"backend/store_test.go":   "package main\n",
+		"backend/go.sum":          "example.com/x v1.0.0 h1:abc\n",
+		"backend/README.md":       "# backend\n",
+		"contracts/api.yaml":      "paths: {}\n",
+		"backend/.done.backend-1": "",
+	})
+
+	got, err := NewContext(0).Add("backend", "missing", "contracts", "backend").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Dirs in the order added, then contracts and docs, entrypoints, other
+	// source and tests; lock files are only ever summarized, and have no
+	// signatures, so they are left out. The dir added twice is read once.
+	want := []string{
+		"Files (6, ~25 tokens)",
+		"backend/README.md",
+		"backend/main.go",
+		"backend/store.go",
+		"backend/store_test.go",
+		"contracts/api.yaml",
+		"Not shown in full",
+	}
+	if heads := sections(got); strings.Join(heads, "|") != strings.Join(want, "|") {
+		t.Errorf("sections = %q, want %q", heads, want)
+	}
+	if !strings.Contains(got, "backend/go.sum (~7 tokens)\n") {
+		t.Errorf("file list doesn't name the lock file:\n%s", got)
+	}
+	if !strings.Contains(got, "1 of 6 files are left out to fit the context budget: 0 shown as signatures only, and backend/go.sum not at all.") {
+		t.Errorf("lock file not reported as left out:\n%s", got)
+	}
+}
+
+func TestBuildBudget(t *testing.T) {
+	big := "package main\n\nfunc Handle() {\n" + strings.Repeat("\t_ = 1\n", 200) + "}\n"
+	useBaseDir(t, map[string]string{
+		"contracts/api.yaml": "paths:\n  /items: {}\n",
+		"backend/main.go":    "package main\n\nfunc main() {}\n",
+		"backend/handler.go": big,
+		"backend/notes.txt":  strings.Repeat("x", 4000),
+	})
+
+	got, err := NewContext(300).Add("contracts", "backend").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"Files (4, ~1371 tokens)",
+		"contracts/api.yaml",
+		"backend/main.go",
+		"backend/handler.go (signatures only)",
+		"backend/notes.txt (signatures only)",
+		"Not shown in full",
+	}
+	if heads := sections(got); strings.Join(heads, "|") != strings.Join(want, "|") {
+		t.Errorf("sections = %q, want %q", heads, want)
+	}
+	if !strings.Contains(got, "package main\nfunc Handle()\n") {
+		t.Errorf("handler.go not summarized to its signatures:\n%s", got)
+	}
+	if tokens(got) > 300 {
+		t.Errorf("context is ~%d tokens, over the budget of 300", tokens(got))
+	}
+}
+
+func TestBuildOmitsWhatDoesNotFit(t *testing.T) {
+	useBaseDir(t, map[string]string{
+		"backend/a.go": "package main\n\nfunc A() {}\n" + strings.Repeat("// pad\n", 100),
+		"backend/b.go": "package main\n\nfunc B() {}\n" + strings.Repeat("// pad\n", 100),
+	})
+
+	const budget = 100
+	got, err := NewContext(budget).Add("backend").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens(got) > budget {
+		t.Errorf("context is ~%d tokens, over the budget of %d", tokens(got), budget)
+	}
+	if !strings.Contains(got, "=== backend/a.go (signatures only) ===\npackage main\nfunc A() {}\n") {
+		t.Errorf("a.go not summarized:\n%s", got)
+	}
+	if !strings.Contains(got, "2 of 2 files are left out to fit the context budget: 1 shown as signatures only, and backend/b.go not at all.") {
+		t.Errorf("b.go not reported as omitted:\n%s", got)
+	}
+}
+
+func TestBuildCollapsesLongFileList(t *testing.T) {
+	files := map[string]string{}
+	for i := range 60 {
+		files[fmt.Sprintf("backend/handlers/h%02d.go", i)] = "package handlers\n"
+		files[fmt.Sprintf("backend/store/s%02d.go", i)] = "package store\n"
+	}
+	files["backend/main.go"] = "package main\n"
+	useBaseDir(t, files)
+
+	const budget = 4000
+	got, err := NewContext(budget).Add("backend").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, _, _ := strings.Cut(got, "\n\n")
+	want := "=== Files (121, ~544 tokens) ===\n" +
+		"backend/ (1 file(s), ~4 tokens)\n" +
+		"backend/handlers/ (60 file(s), ~300 tokens)\n" +
+		"backend/store/ (60 file(s), ~240 tokens)"
+	if header != want {
+		t.Errorf("header =\n%s\nwant\n%s", header, want)
+	}
+	if tokens(got) > budget {
+		t.Errorf("context is ~%d tokens, over the budget of %d", tokens(got), budget)
+	}
+}
+
+func TestBuildCollapsesToSources(t *testing.T) {
+	files := map[string]string{}
+	for i := range 40 {
+		files[fmt.Sprintf("backend/pkg%02d/f.go", i)] = "package p\n"
+	}
+	useBaseDir(t, files)
+
+	got, err := NewContext(400).Add("backend").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, _, _ := strings.Cut(got, "\n\n")
+	if want := "=== Files (40, ~120 tokens) ===\nbackend/ (40 file(s), ~120 tokens)"; header != want {
+		t.Errorf("header =\n%s\nwant\n%s", header, want)
+	}
+}
+
+func TestBuildFiles(t *testing.T) {
+	useBaseDir(t, map[string]string{
+		"backend/handler.go": "package main\n",
+		"backend/other.go":   "package main\n",
+		"backend/image.png":  "\x89PNG\x00\x00",
+		"contracts/api.yaml": "paths: {}\n",
+	})
+
+	got, err := NewContext(0).AddFiles("backend/handler.go", "backend/image.png", "backend/gone.go").Add("contracts").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Files (2, ~7 tokens)", "backend/handler.go", "contracts/api.yaml"}
+	if heads := sections(got); strings.Join(heads, "|") != strings.Join(want, "|") {
+		t.Errorf("sections = %q, want %q", heads, want)
+	}
+}
+
+func TestBuildEmpty(t *testing.T) {
+	useBaseDir(t, nil)
+
+	got, err := NewContext(100).Add("backend").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "=== Files (0, ~0 tokens) ===\n(none)\n\n"; got != want {
+		t.Errorf("Build() = %q, want %q", got, want)
+	}
+}
+
+func TestSummarizeTruncatesAtRune(t *testing.T) {
+	line := "func Greet() string { return \"x" + strings.Repeat("é", 200) + "\" }"
+	got := summarize("greet.go", "package main\n\n"+line+"\n")
+
+	sig := strings.TrimPrefix(got, "package main\n")
+	if !strings.HasSuffix(sig, "...") || len(sig) > 203 {
+		t.Errorf("signature not shortened to 200 bytes: %q", sig)
+	}
+	if !utf8.ValidString(got) {
+		t.Errorf("summarize() = %q, not valid UTF-8", got)
+	}
+}
@@ -0,0 +1,137 @@
+package artifact
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoredDirs are dependency, VCS and build output dirs never read into a
+// prompt, wherever they appear.
+var ignoredDirs = map[string]bool{
+	".git": true, "node_modules": true, "vendor": true, "dist": true,
+	"build": true, "target": true, "coverage": true, ".next": true,
+	"__pycache__": true, ".venv": true, ".swarm": true,
+}
+
+// ignoreRule is one pattern from a .gitignore file.
+type ignoreRule struct {
+	base    string // dir of the .gitignore, relative to BaseDir, slash-separated
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreRules are the .gitignore rules in effect for a walk, in file order:
+// ancestors' first, so that later, deeper rules take precedence.
+type ignoreRules []ignoreRule
+
+// load appends the rules of dir/.gitignore, if there is one. dir is relative
+// to BaseDir.
+func (rs *ignoreRules) load(dir string) {
+	data, err := os.ReadFile(filepath.Join(BaseDir, dir, ".gitignore"))
+	if err != nil {
+		return
+	}
+	base := filepath.ToSlash(dir)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, " \r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r := ignoreRule{base: base}
+		if r.negate = strings.HasPrefix(line, "!"); r.negate {
+			line = line[1:]
+		}
+		if r.dirOnly = strings.HasSuffix(line, "/"); r.dirOnly {
+			line = strings.TrimSuffix(line, "/")
+		}
+		// A pattern with a slash is anchored to the .gitignore's dir;
+		// otherwise it matches a name at any depth below it.
+		if !strings.Contains(line, "/") {
+			line = "**/" + line
+		}
+		r.re = globRegexp(strings.TrimPrefix(line, "/"))
+		*rs = append(*rs, r)
+	}
+}
+
+// ignored reports whether rel, relative to BaseDir, is excluded by the rules.
+// The last matching rule wins.
+func (rs ignoreRules) ignored(rel string, isDir bool) bool {
+	rel = filepath.ToSlash(rel)
+	ignored := false
+	for _, r := range rs {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		p := rel
+		if r.base != "." && r.base != "" {
+			var ok bool
+			if p, ok = strings.CutPrefix(rel, r.base+"/"); !ok {
+				continue
+			}
+		}
+		if r.re.MatchString(p) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// globRegexp compiles a gitignore glob: * and ? stay within a path element,
+// ** spans elements, and [...] is a character class.
+func globRegexp(glob string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("(/.*)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return regexp.MustCompile("^" + regexp.QuoteMeta(glob) + "$") // malformed class: match literally
+	}
+	return re
+}
+
+// ancestors returns the dirs from BaseDir down to dir's parent, each relative
+// to BaseDir.
+func ancestors(dir string) []string {
+	dirs := []string{"."}
+	parts := strings.Split(filepath.ToSlash(filepath.Clean(dir)), "/")
+	for i := 1; i < len(parts); i++ {
+		dirs = append(dirs, path.Join(parts[:i]...))
+	}
+	return dirs
+}
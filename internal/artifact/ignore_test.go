@@ -0,0 +1,136 @@
+package artifact
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGlobRegexp(t *testing.T) {
+	tests := []struct {
+		glob    string
+		match   []string
+		noMatch []string
+	}{
+		{"*.log", []string{"app.log", ".log"}, []string{"logs/app.log", "app.log.1"}},
+		{"build?", []string{"build1", "builds"}, []string{"build", "build/1", "build12"}},
+		{"**/tmp", []string{"tmp", "a/tmp", "a/b/tmp"}, []string{"tmpx", "a/tmp/x"}},
+		{"out/**", []string{"out", "out/a", "out/a/b"}, []string{"outx", "x/out/a"}},
+		{"a/**/z", []string{"a/z", "a/b/z", "a/b/c/z"}, []string{"az", "b/a/z"}},
+		{"docs/*.md", []string{"docs/a.md"}, []string{"docs/x/a.md", "a.md"}},
+		{"file[0-9].txt", []string{"file1.txt"}, []string{"filea.txt", "file10.txt"}},
+		{"file[!0-9].txt", []string{"filea.txt"}, []string{"file1.txt"}},
+		{"a.b+c", []string{"a.b+c"}, []string{"axb+c", "a.bbc"}},
+		{"[unclosed", []string{"[unclosed"}, []string{"u"}},
+		{"[z-a]", []string{"[z-a]"}, []string{"z", "a"}}, // invalid class: literal
+	}
+	for _, tt := range tests {
+		re := globRegexp(tt.glob)
+		for _, p := range tt.match {
+			if !re.MatchString(p) {
+				t.Errorf("globRegexp(%q) doesn't match %q (regexp %s)", tt.glob, p, re)
+			}
+		}
+		for _, p := range tt.noMatch {
+			if re.MatchString(p) {
+				t.Errorf("globRegexp(%q) matches %q (regexp %s)", tt.glob, p, re)
+			}
+		}
+	}
+}
+
+func TestIgnored(t *testing.T) {
+	useBaseDir(t, map[string]string{
+		".gitignore":         "*.log\n/secret.txt\ntmp/\n# comment\n\n!keep.log\n",
+		"web/.gitignore":     "generated/\n!tmp/\nassets/*.map\n",
+		"web/sub/.gitignore": "keep.log\n",
+	})
+	var rules ignoreRules
+	for _, dir := range []string{".", "web", "web/sub"} {
+		rules.load(dir)
+	}
+
+	tests := []struct {
+		rel   string
+		isDir bool
+		want  bool
+	}{
+		{"app.log", false, true},
+		{"web/deep/app.log", false, true},          // unanchored: any depth
+		{"keep.log", false, false},                 // negated by a later rule
+		{"web/sub/keep.log", false, true},          // deeper rule wins
+		{"secret.txt", false, true},                // anchored to the root
+		{"web/secret.txt", false, false},           // ...and only there
+		{"tmp", true, true},                        // dir-only pattern
+		{"tmp", false, false},                      // ...doesn't match a file
+		{"web/tmp", true, false},                   // re-included below web
+		{"web/generated", true, true},              // rule from web/.gitignore
+		{"generated", true, false},                 // ...doesn't apply above it
+		{"web/assets/app.js.map", false, true},     // anchored with a slash
+		{"web/assets/js/app.js.map", false, false}, // * stays in one element
+		{"webapp/generated", true, false},          // base is a path prefix, not a string prefix
+		{"web/sub/generated", true, true},          // inherited by subdirs
+	}
+	for _, tt := range tests {
+		if got := rules.ignored(tt.rel, tt.isDir); got != tt.want {
+			t.Errorf("ignored(%q, dir=%v) = %v, want %v", tt.rel, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestReadAllSkips(t *testing.T) {
+	useBaseDir(t, map[string]string{
+		".gitignore":                "*.tmp\n",
+		"backend/.gitignore":        "bin/\n",
+		"backend/main.go":           "package main\n",
+		"backend/scratch.tmp":       "x\n",
+		"backend/bin/server":        "binary\n",
+		"backend/node_modules/a.js": "x\n",
+		"backend/.done.backend":     "",
+		"backend/logo.png":          "\x89PNG\x00",
+		"backend/pkg/util.go":       "package pkg\n",
+	})
+
+	files, err := ReadAll("backend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range files {
+		got = append(got, f.Path)
+	}
+	want := []string{".gitignore", "main.go", "pkg/util.go"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ReadAll() paths = %q, want %q", got, want)
+	}
+}
+
+func TestReadTextTruncatesAtRune(t *testing.T) {
+	tests := []struct {
+		name, content string
+	}{
+		{"one line of multi-byte runes", "x" + strings.Repeat("é", MaxFileSize)},
+		{"four-byte runes", "xy" + strings.Repeat("😀", MaxFileSize/3)},
+		{"several lines", strings.Repeat("héllo\n", MaxFileSize/5)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useBaseDir(t, map[string]string{"f.txt": tt.content})
+
+			f, err := readText(filepath.Join(BaseDir, "f.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f == nil {
+				t.Fatal("readText() took a truncated text file for binary")
+			}
+			if !f.Truncated || len(f.Content) > MaxFileSize || len(f.Content) < MaxFileSize-8 {
+				t.Errorf("Truncated = %v, len(Content) = %d; want a cut within a line of %d", f.Truncated, len(f.Content), MaxFileSize)
+			}
+			if !strings.HasPrefix(tt.content, f.Content) {
+				t.Error("Content isn't a prefix of the file")
+			}
+		})
+	}
+}
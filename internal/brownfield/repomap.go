@@ -0,0 +1,261 @@
+// Package brownfield points the swarm at an existing repository: it maps the
+// repository's structure for the architect, checks it out as a git worktree
+// for the agents to change, and turns their changes into a branch and a
+// patch set.
+package brownfield
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxListed caps each section of the map so a large repo stays readable.
+const maxListed = 200
+
+// skipDirs are never descended into when mapping.
+var skipDirs = map[string]bool{
+	".git": true, ".swarm": true, "node_modules": true, "vendor": true,
+	"dist": true, "build": true, ".next": true, "coverage": true,
+}
+
+var (
+	goPackage = regexp.MustCompile(`^package\s+(\w+)`)
+	goRoute   = regexp.MustCompile(`\.(HandleFunc|Handle|GET|POST|PUT|PATCH|DELETE|Get|Post|Put|Patch|Delete)\(\s*"([^"]+)"`)
+	tsRoute   = regexp.MustCompile(`\b(?:app|router|server|api)\.(get|post|put|patch|delete|all)\(\s*['"` + "`" + `]([^'"` + "`" + `]+)`)
+)
+
+// Map describes the structure of the repository at root: its Go packages and
+// JS/TS packages, entrypoints, HTTP routes and tests.
+type Map struct {
+	GoModule    string
+	Packages    []Package
+	Entrypoints []string
+	Routes      []Route
+	Tests       []string
+}
+
+// Package is a Go package or a JS/TS package.json.
+type Package struct {
+	Path  string // directory relative to the repo root
+	Name  string
+	Lang  string // "go" or "js"
+	Files int
+	Tests int
+}
+
+// Route is an HTTP route registration found in the source.
+type Route struct {
+	Method string
+	Path   string
+	File   string // file:line relative to the repo root
+}
+
+// BuildMap walks the repository at root.
+func BuildMap(root string) (*Map, error) {
+	m := &Map{}
+	if data, err := os.ReadFile(filepath.Join(root, "go.mod")); err == nil {
+		m.GoModule = moduleLine(string(data))
+	}
+
+	goPkgs := map[string]*Package{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && (skipDirs[d.Name()] || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		dir := filepath.Dir(rel)
+		name := d.Name()
+
+		switch {
+		case strings.HasSuffix(name, "_test.go"):
+			m.Tests = append(m.Tests, rel)
+			goPkg(goPkgs, dir).Tests++
+		case strings.HasSuffix(name, ".go"):
+			p := goPkg(goPkgs, dir)
+			p.Files++
+			return m.scanSource(root, rel, p, goRoute)
+		case name == "package.json":
+			return m.addJSPackage(root, rel)
+		case isJSTest(name):
+			m.Tests = append(m.Tests, rel)
+		case isJS(name):
+			return m.scanSource(root, rel, nil, tsRoute)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("map %s: %w", root, err)
+	}
+
+	for _, p := range goPkgs {
+		if p.Files == 0 {
+			continue // tests only
+		}
+		m.Packages = append(m.Packages, *p)
+		if p.Name == "main" {
+			m.Entrypoints = append(m.Entrypoints, p.Path+" (go main)")
+		}
+	}
+	sort.Slice(m.Packages, func(i, j int) bool { return m.Packages[i].Path < m.Packages[j].Path })
+	sort.Strings(m.Entrypoints)
+	return m, nil
+}
+
+func goPkg(pkgs map[string]*Package, dir string) *Package {
+	p, ok := pkgs[dir]
+	if !ok {
+		p = &Package{Path: dir, Lang: "go"}
+		pkgs[dir] = p
+	}
+	return p
+}
+
+// scanSource records the route registrations in a source file and, for Go,
+// the package name.
+func (m *Map) scanSource(root, rel string, p *Package, route *regexp.Regexp) error {
+	f, err := os.Open(filepath.Join(root, rel))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if p != nil && p.Name == "" {
+			if match := goPackage.FindStringSubmatch(line); match != nil {
+				p.Name = match[1]
+			}
+		}
+		for _, match := range route.FindAllStringSubmatch(line, -1) {
+			method, path := routeMethod(match[1], match[2])
+			m.Routes = append(m.Routes, Route{
+				Method: method,
+				Path:   path,
+				File:   fmt.Sprintf("%s:%d", rel, n),
+			})
+		}
+	}
+	return nil // a line too long to scan just ends the scan
+}
+
+// routeMethod names the method a route registration matches. Handle and
+// HandleFunc match any method unless the pattern names one ("GET /users").
+func routeMethod(fn, pattern string) (method, path string) {
+	if fn != "Handle" && fn != "HandleFunc" {
+		return strings.ToUpper(fn), pattern
+	}
+	if method, path, ok := strings.Cut(pattern, " "); ok {
+		return method, strings.TrimSpace(path)
+	}
+	return "ANY", pattern
+}
+
+// addJSPackage records a package.json, its main entrypoint and its scripts.
+func (m *Map) addJSPackage(root, rel string) error {
+	data, err := os.ReadFile(filepath.Join(root, rel))
+	if err != nil {
+		return err
+	}
+	var pkg struct {
+		Name    string            `json:"name"`
+		Main    string            `json:"main"`
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil // not ours to validate
+	}
+	dir := filepath.Dir(rel)
+	m.Packages = append(m.Packages, Package{Path: dir, Name: pkg.Name, Lang: "js"})
+	if pkg.Main != "" {
+		m.Entrypoints = append(m.Entrypoints, filepath.Join(dir, pkg.Main)+" (package.json main)")
+	}
+	for _, script := range []string{"start", "dev", "serve"} {
+		if cmd, ok := pkg.Scripts[script]; ok {
+			m.Entrypoints = append(m.Entrypoints, fmt.Sprintf("%s: npm run %s (%s)", dir, script, cmd))
+		}
+	}
+	return nil
+}
+
+func isJS(name string) bool {
+	switch filepath.Ext(name) {
+	case ".ts", ".tsx", ".js", ".jsx", ".mjs":
+		return !strings.HasSuffix(name, ".d.ts")
+	}
+	return false
+}
+
+func isJSTest(name string) bool {
+	return isJS(name) && (strings.Contains(name, ".test.") || strings.Contains(name, ".spec."))
+}
+
+func moduleLine(gomod string) string {
+	for _, line := range strings.Split(gomod, "\n") {
+		if mod, ok := strings.CutPrefix(strings.TrimSpace(line), "module "); ok {
+			return strings.TrimSpace(mod)
+		}
+	}
+	return ""
+}
+
+// Markdown renders the map for the architect's prompt.
+func (m *Map) Markdown() string {
+	var b strings.Builder
+	b.WriteString("# Repository Map\n")
+	if m.GoModule != "" {
+		fmt.Fprintf(&b, "\nGo module: `%s`\n", m.GoModule)
+	}
+
+	b.WriteString("\n## Packages\n\n")
+	for i, p := range m.Packages {
+		if i == maxListed {
+			fmt.Fprintf(&b, "- ... %d more\n", len(m.Packages)-i)
+			break
+		}
+		switch p.Lang {
+		case "go":
+			fmt.Fprintf(&b, "- `%s` — package %s, %d files, %d test files\n", p.Path, p.Name, p.Files, p.Tests)
+		default:
+			fmt.Fprintf(&b, "- `%s` — npm package %s\n", p.Path, p.Name)
+		}
+	}
+
+	writeList(&b, "Entrypoints", m.Entrypoints)
+	routes := make([]string, len(m.Routes))
+	for i, r := range m.Routes {
+		routes[i] = fmt.Sprintf("%s %s — %s", r.Method, r.Path, r.File)
+	}
+	writeList(&b, "Routes", routes)
+	writeList(&b, "Tests", m.Tests)
+	return b.String()
+}
+
+func writeList(b *strings.Builder, title string, items []string) {
+	fmt.Fprintf(b, "\n## %s\n\n", title)
+	if len(items) == 0 {
+		b.WriteString("none found\n")
+		return
+	}
+	for i, item := range items {
+		if i == maxListed {
+			fmt.Fprintf(b, "- ... %d more\n", len(items)-i)
+			return
+		}
+		fmt.Fprintf(b, "- %s\n", item)
+	}
+}
@@ -0,0 +1,115 @@
+package brownfield
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Target is an existing repository the swarm changes through a worktree on
+// its own branch, leaving the repository's checkout untouched.
+type Target struct {
+	Repo     string `json:"repo"`     // repository root
+	Branch   string `json:"branch"`   // branch the worktree is on
+	Base     string `json:"base"`     // commit the branch started from
+	Worktree string `json:"worktree"` // where the agents make their changes
+}
+
+// RepoRoot returns the top level of the git repository containing path.
+func RepoRoot(path string) (string, error) {
+	out, err := git(path, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", fmt.Errorf("%s is not a git repository: %w", path, err)
+	}
+	return out, nil
+}
+
+// Checkout creates branch at the repository's HEAD and checks it out in a new
+// worktree at dir.
+func Checkout(repo, branch, dir string) (*Target, error) {
+	base, err := git(repo, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("resolve HEAD of %s: %w", repo, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+		return nil, fmt.Errorf("mkdir %s: %w", filepath.Dir(dir), err)
+	}
+	if _, err := git(repo, "worktree", "add", "-b", branch, dir, base); err != nil {
+		return nil, fmt.Errorf("create worktree: %w", err)
+	}
+	return &Target{Repo: repo, Branch: branch, Base: base, Worktree: dir}, nil
+}
+
+// Commit commits everything the agents changed in the worktree, bar sentinel
+// files, and writes the branch's commits as a patch set to patchDir. It
+// returns the patch files, none if nothing changed.
+func (t *Target) Commit(message, patchDir string) ([]string, error) {
+	if _, err := git(t.Worktree, "add", "-A", "--", ".", ":(exclude,glob)**/.done.*"); err != nil {
+		return nil, fmt.Errorf("stage changes: %w", err)
+	}
+	changed, err := t.staged()
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		if _, err := git(t.Worktree, "commit", "-m", message); err != nil {
+			return nil, fmt.Errorf("commit changes: %w", err)
+		}
+	}
+
+	out, err := git(t.Worktree, "format-patch", "--output-directory", patchDir, t.Base+"..HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("write patches: %w", err)
+	}
+	if out == "" {
+		return nil, nil
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// staged reports whether the worktree's index differs from HEAD: git diff
+// --quiet exits 1 when it does, and with any other status on a real failure.
+func (t *Target) staged() (bool, error) {
+	_, err := git(t.Worktree, "diff", "--cached", "--quiet")
+	var exit *exec.ExitError
+	switch {
+	case err == nil:
+		return false, nil
+	case errors.As(err, &exit) && exit.ExitCode() == 1:
+		return true, nil
+	}
+	return false, fmt.Errorf("check staged changes: %w", err)
+}
+
+// Remove deletes the worktree, keeping its branch and commits. Unless force
+// is set, git refuses to remove a worktree with uncommitted changes.
+func (t *Target) Remove(force bool) error {
+	args := []string{"worktree", "remove", t.Worktree}
+	if force {
+		args = []string{"worktree", "remove", "--force", t.Worktree}
+	}
+	if _, err := git(t.Repo, args...); err != nil {
+		return fmt.Errorf("remove worktree: %w", err)
+	}
+	return nil
+}
+
+// git runs a git command in dir and returns its trimmed stdout. A failed
+// command's error wraps the *exec.ExitError.
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s (%w)", args[0], msg, err)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,160 @@
+// Package cache memoizes approved task outputs across runs. Each entry is
+// keyed by a hash of everything that went into the task, so a later task with
+// identical inputs can reuse the outputs instead of launching an agent.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultDir is where the cache lives, shared by every run in the project.
+const DefaultDir = ".swarm/cache"
+
+// manifestFile lists an entry's files and where they came from.
+const manifestFile = "manifest.json"
+
+// Cache is a directory of entries, one per key: <dir>/<key>/manifest.json and
+// the cached files under <dir>/<key>/files/.
+type Cache struct {
+	dir string
+}
+
+// New returns a cache rooted at dir.
+func New(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// Entry describes a cached task output.
+type Entry struct {
+	Task     string    `json:"task"`
+	Role     string    `json:"role"`
+	RunID    string    `json:"run_id,omitempty"`
+	Files    []string  `json:"files"` // relative to the task's output dir
+	StoredAt time.Time `json:"stored_at"`
+}
+
+// Key accumulates the inputs of a task into a cache key.
+type Key struct {
+	h hash.Hash
+}
+
+// NewKey starts an empty key.
+func NewKey() *Key {
+	return &Key{h: sha256.New()}
+}
+
+// Add mixes a labelled value into the key. Values are length-prefixed so
+// adjacent ones can't run together.
+func (k *Key) Add(label, value string) {
+	fmt.Fprintf(k.h, "%s:%d:%s\n", label, len(value), value)
+}
+
+// AddDir mixes in the path and content of every file under dir, in sorted
+// order. Sentinel files are left out; a missing dir adds nothing.
+func (k *Key) AddDir(label, dir string) error {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if !d.IsDir() && !strings.HasPrefix(d.Name(), ".done") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("hash %s: %w", dir, err)
+	}
+	sort.Strings(files)
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("hash %s: %w", path, err)
+		}
+		rel, _ := filepath.Rel(dir, path)
+		k.Add(label+"/"+filepath.ToSlash(rel), string(data))
+	}
+	return nil
+}
+
+// Sum returns the key as a hex string.
+func (k *Key) Sum() string {
+	return hex.EncodeToString(k.h.Sum(nil))
+}
+
+// Has reports whether key has an entry.
+func (c *Cache) Has(key string) bool {
+	_, err := os.Stat(filepath.Join(c.dir, key, manifestFile))
+	return err == nil
+}
+
+// Store copies e.Files from srcDir into a new entry for key, replacing any
+// existing one.
+func (c *Cache) Store(key, srcDir string, e Entry) error {
+	entryDir := filepath.Join(c.dir, key)
+	tmp := entryDir + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		return fmt.Errorf("store %s: %w", key, err)
+	}
+	for _, f := range e.Files {
+		if err := copyFile(filepath.Join(srcDir, f), filepath.Join(tmp, "files", f)); err != nil {
+			os.RemoveAll(tmp)
+			return fmt.Errorf("store %s: %w", key, err)
+		}
+	}
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(tmp, manifestFile), data, 0o644); err != nil {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("store %s: %w", key, err)
+	}
+	if err := os.RemoveAll(entryDir); err != nil {
+		return fmt.Errorf("store %s: %w", key, err)
+	}
+	return os.Rename(tmp, entryDir)
+}
+
+// Restore copies the files of key's entry into dstDir and returns the entry.
+func (c *Cache) Restore(key, dstDir string) (Entry, error) {
+	entryDir := filepath.Join(c.dir, key)
+	var e Entry
+	data, err := os.ReadFile(filepath.Join(entryDir, manifestFile))
+	if err != nil {
+		return e, fmt.Errorf("restore %s: %w", key, err)
+	}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return e, fmt.Errorf("restore %s: %w", key, err)
+	}
+	for _, f := range e.Files {
+		if err := copyFile(filepath.Join(entryDir, "files", f), filepath.Join(dstDir, f)); err != nil {
+			return e, fmt.Errorf("restore %s: %w", key, err)
+		}
+	}
+	return e, nil
+}
+
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0o644)
+}
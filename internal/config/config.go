@@ -0,0 +1,136 @@
+// Package config loads the optional per-project swarm configuration from
+// .swarm/config.yaml.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/hubenschmidt/claude-dag/internal/model"
+)
+
+// DefaultPath is the project config file, relative to the working directory.
+const DefaultPath = ".swarm/config.yaml"
+
+// Config is the top-level configuration.
+type Config struct {
+	// Retry overrides model.DefaultRetryPolicy for every role.
+	Retry *model.RetryPolicy `yaml:"retry"`
+
+	Roles map[model.AgentRole]RoleConfig `yaml:"roles"`
+}
+
+// RoleConfig holds settings applied to every task of a role.
+type RoleConfig struct {
+	// RequiresApproval holds tasks of this role for human sign-off after
+	// their reviewer approves.
+	RequiresApproval bool `yaml:"requires_approval"`
+
+	// Duration is the expected run time of one task, used by the scheduler
+	// instead of historical averages (e.g. "10m").
+	Duration time.Duration `yaml:"duration"`
+
+	// Timeout fails a task of this role that runs longer, as a timeout.
+	// Zero means no limit.
+	Timeout time.Duration `yaml:"timeout"`
+
+	// Verify is a shell command run in the task's output directory once its
+	// agent finishes. A non-zero exit is a verification failure.
+	Verify string `yaml:"verify"`
+
+	// Retry overrides the project-wide retry policy for this role.
+	Retry *model.RetryPolicy `yaml:"retry"`
+
+	// Review replaces the single generic reviewer of this role's tasks with
+	// a panel.
+	Review *ReviewConfig `yaml:"review"`
+}
+
+// ReviewConfig sets up a review panel: one reviewer per lens, all running in
+// parallel, with verdicts combined by the consensus rule.
+type ReviewConfig struct {
+	// Lenses name the reviewers' focus. Each lens adds the prompt
+	// review-<lens>.md to the reviewer's system prompt.
+	Lenses []string `yaml:"lenses"`
+
+	// Consensus is "all" (the default), "majority" or "blocking".
+	Consensus model.Consensus `yaml:"consensus"`
+}
+
+// validate rejects unknown consensus rules and duplicate lenses.
+func (r *ReviewConfig) validate() error {
+	if r == nil {
+		return nil
+	}
+	switch r.Consensus {
+	case "", model.ConsensusAll, model.ConsensusMajority, model.ConsensusBlocking:
+	default:
+		return fmt.Errorf("unknown consensus %q (want all, majority or blocking)", r.Consensus)
+	}
+	seen := map[string]bool{}
+	for _, lens := range r.Lenses {
+		if lens == "" || seen[lens] {
+			return fmt.Errorf("lenses must be unique and non-empty")
+		}
+		seen[lens] = true
+	}
+	return nil
+}
+
+// Load reads the config at path. A missing file yields an empty config.
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// validate rejects retry policies naming unknown failure classes and
+// malformed review panels.
+func (c *Config) validate() error {
+	if err := c.Retry.Validate(); err != nil {
+		return fmt.Errorf("retry: %w", err)
+	}
+	for role, rc := range c.Roles {
+		if err := rc.Retry.Validate(); err != nil {
+			return fmt.Errorf("roles.%s.retry: %w", role, err)
+		}
+		if err := rc.Review.validate(); err != nil {
+			return fmt.Errorf("roles.%s.review: %w", role, err)
+		}
+	}
+	return nil
+}
+
+// RetryPolicy returns the retry policy for tasks of role: the default,
+// overridden by the project-wide policy, overridden by the role's own.
+func (c *Config) RetryPolicy(role model.AgentRole) model.RetryPolicy {
+	if c == nil {
+		return model.DefaultRetryPolicy
+	}
+	return model.DefaultRetryPolicy.With(c.Retry).With(c.Roles[role].Retry)
+}
+
+// Role returns the settings for role, or zero settings if none are configured.
+func (c *Config) Role(role model.AgentRole) RoleConfig {
+	if c == nil {
+		return RoleConfig{}
+	}
+	return c.Roles[role]
+}
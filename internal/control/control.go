@@ -0,0 +1,140 @@
+// Package control implements the orchestrator's Unix domain control socket.
+// Each connection carries one JSON request line and receives one JSON
+// response line.
+package control
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"time"
+)
+
+// Commands understood by the orchestrator.
+const (
+	CmdPause       = "pause"       // stop launching new tasks
+	CmdResume      = "resume"      // resume launching
+	CmdRetry       = "retry"       // re-queue Task with Feedback, killing its pane if running
+	CmdCancel      = "cancel"      // kill Task's pane and stop it from running again
+	CmdConcurrency = "concurrency" // set the max concurrent tasks to Value
+	CmdAdd         = "add"         // add a task built from ID, Role, Description, DependsOn
+	CmdApprove     = "approve"     // sign off on Task awaiting human approval
+	CmdReject      = "reject"      // send Task awaiting approval back with Feedback
+	CmdStop        = "stop"        // wind the run down as the first Ctrl-C does
+)
+
+const ioTimeout = 10 * time.Second
+
+// Request is a single control command.
+type Request struct {
+	Command     string   `json:"command"`
+	Task        string   `json:"task,omitempty"`
+	Feedback    string   `json:"feedback,omitempty"`
+	Value       int      `json:"value,omitempty"`
+	Role        string   `json:"role,omitempty"`
+	Description string   `json:"description,omitempty"`
+	DependsOn   []string `json:"depends_on,omitempty"`
+
+	RequiresApproval bool `json:"requires_approval,omitempty"`
+}
+
+// Response reports the outcome of a Request.
+type Response struct {
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Handler executes a request, returning a human-readable result.
+type Handler func(Request) (string, error)
+
+// Server accepts control connections on a Unix socket.
+type Server struct {
+	path     string
+	listener net.Listener
+	handler  Handler
+}
+
+// Listen binds the socket at path, replacing a stale socket file left by a
+// previous process, and serves requests with h until Close.
+func Listen(path string, h Handler) (*Server, error) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("remove stale socket: %w", err)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listen %s: %w", path, err)
+	}
+	s := &Server{path: path, listener: l, handler: h}
+	go s.serve()
+	return s, nil
+}
+
+// Close stops accepting connections and removes the socket file.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	_ = os.Remove(s.path)
+	return err
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			log.Printf("[control] accept: %v", err)
+			continue
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(ioTimeout))
+
+	var req Request
+	line, err := bufio.NewReader(conn).ReadBytes('\n')
+	if err == nil {
+		err = json.Unmarshal(line, &req)
+	}
+
+	var resp Response
+	if err != nil {
+		resp.Error = fmt.Sprintf("bad request: %v", err)
+	} else if msg, herr := s.handler(req); herr != nil {
+		resp.Error = herr.Error()
+	} else {
+		resp = Response{OK: true, Message: msg}
+	}
+
+	_ = json.NewEncoder(conn).Encode(resp)
+}
+
+// Send delivers req to the socket at path and returns the handler's message.
+func Send(path string, req Request) (string, error) {
+	conn, err := net.DialTimeout("unix", path, ioTimeout)
+	if err != nil {
+		return "", fmt.Errorf("connect %s: %w", path, err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(ioTimeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return "", fmt.Errorf("send: %w", err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return "", fmt.Errorf("read response: %w", err)
+	}
+	if !resp.OK {
+		return "", errors.New(resp.Error)
+	}
+	return resp.Message, nil
+}
@@ -0,0 +1,375 @@
+// Package devops checks the container and run configuration the devops agent
+// generates: that each file parses and that the paths it references exist in
+// the artifacts tree.
+package devops
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// composeNames are the file names docker compose looks for, in its order.
+var composeNames = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+// dockerInstructions are the instructions a Dockerfile line may start with.
+var dockerInstructions = map[string]bool{
+	"ADD": true, "ARG": true, "CMD": true, "COPY": true, "ENTRYPOINT": true,
+	"ENV": true, "EXPOSE": true, "FROM": true, "HEALTHCHECK": true, "LABEL": true,
+	"MAINTAINER": true, "ONBUILD": true, "RUN": true, "SHELL": true,
+	"STOPSIGNAL": true, "USER": true, "VOLUME": true, "WORKDIR": true,
+}
+
+var envKey = regexp.MustCompile(`^(export\s+)?[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Check inspects the files under dir, which must lie inside root, the
+// artifacts tree. It returns one line per problem found; none means the
+// configuration is sound. Paths in problems are relative to root.
+func Check(root, dir string) []string {
+	c := &checker{root: root}
+
+	compose := ""
+	for _, name := range composeNames {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			compose = filepath.Join(dir, name)
+			break
+		}
+	}
+	if compose == "" {
+		c.problem(dir, "no compose file (want one of %s)", strings.Join(composeNames, ", "))
+	} else {
+		c.checkCompose(compose)
+	}
+
+	var makefile bool
+	_ = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasPrefix(d.Name(), ".done") {
+			return nil
+		}
+		switch name := d.Name(); {
+		case name == "Makefile":
+			makefile = true
+			c.checkMakefile(path)
+		case name == "Dockerfile" || strings.HasSuffix(name, ".Dockerfile"):
+			if !c.dockerfiles[path] {
+				c.checkDockerfile(path, "")
+			}
+		case name == ".env.example" || strings.HasSuffix(name, ".env.template") || strings.HasSuffix(name, ".env.example"):
+			c.checkEnvTemplate(path)
+		}
+		return nil
+	})
+	if !makefile {
+		c.problem(dir, "no Makefile")
+	}
+	return c.problems
+}
+
+type checker struct {
+	root        string
+	problems    []string
+	dockerfiles map[string]bool // checked against their compose build context
+}
+
+func (c *checker) problem(path, format string, args ...any) {
+	if rel, err := filepath.Rel(c.root, path); err == nil {
+		path = rel
+	}
+	c.problems = append(c.problems, path+": "+fmt.Sprintf(format, args...))
+}
+
+// resolve joins ref onto base and reports whether the result exists and
+// stays inside the artifacts tree.
+func (c *checker) resolve(base, ref string) (string, bool) {
+	path := filepath.Clean(filepath.Join(base, ref))
+	if rel, err := filepath.Rel(c.root, path); err != nil || strings.HasPrefix(rel, "..") {
+		return path, false
+	}
+	_, err := os.Stat(path)
+	return path, err == nil
+}
+
+type composeFile struct {
+	Services map[string]composeService `yaml:"services"`
+}
+
+type composeService struct {
+	Image   string    `yaml:"image"`
+	Build   yaml.Node `yaml:"build"`
+	EnvFile yaml.Node `yaml:"env_file"`
+	Volumes []any     `yaml:"volumes"`
+}
+
+// checkCompose parses a compose file and checks every build context,
+// Dockerfile, env file and bind-mounted host path it references.
+func (c *checker) checkCompose(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		c.problem(path, "%v", err)
+		return
+	}
+	var cf composeFile
+	if err := yaml.Unmarshal(data, &cf); err != nil {
+		c.problem(path, "invalid YAML: %v", err)
+		return
+	}
+	if len(cf.Services) == 0 {
+		c.problem(path, "no services")
+		return
+	}
+
+	base := filepath.Dir(path)
+	names := make([]string, 0, len(cf.Services))
+	for name := range cf.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		svc := cf.Services[name]
+		context, dockerfile := buildRefs(svc.Build)
+		if context == "" && svc.Image == "" {
+			c.problem(path, "service %s has neither build nor image", name)
+		}
+		if context != "" {
+			ctxPath, ok := c.resolve(base, context)
+			if !ok {
+				c.problem(path, "service %s: build context %s does not exist in the artifacts tree", name, context)
+			} else {
+				df, ok := c.resolve(ctxPath, dockerfile)
+				if !ok {
+					c.problem(path, "service %s: Dockerfile %s does not exist in %s", name, dockerfile, context)
+				} else {
+					if c.dockerfiles == nil {
+						c.dockerfiles = map[string]bool{}
+					}
+					c.dockerfiles[df] = true
+					c.checkDockerfile(df, ctxPath)
+				}
+			}
+		}
+		for _, env := range stringList(svc.EnvFile) {
+			if _, ok := c.resolve(base, env); !ok {
+				c.problem(path, "service %s: env_file %s does not exist", name, env)
+			}
+		}
+		for _, v := range svc.Volumes {
+			spec, ok := v.(string)
+			if !ok {
+				continue
+			}
+			host, _, _ := strings.Cut(spec, ":")
+			if !strings.HasPrefix(host, ".") {
+				continue // named volume or absolute host path
+			}
+			if _, ok := c.resolve(base, host); !ok {
+				c.problem(path, "service %s: volume source %s does not exist", name, host)
+			}
+		}
+	}
+}
+
+// buildRefs reads a compose build entry, either a context path or a
+// {context, dockerfile} mapping.
+func buildRefs(n yaml.Node) (context, dockerfile string) {
+	dockerfile = "Dockerfile"
+	switch n.Kind {
+	case yaml.ScalarNode:
+		return n.Value, dockerfile
+	case yaml.MappingNode:
+		var b struct {
+			Context    string `yaml:"context"`
+			Dockerfile string `yaml:"dockerfile"`
+		}
+		if err := n.Decode(&b); err != nil {
+			return "", dockerfile
+		}
+		if b.Dockerfile != "" {
+			dockerfile = b.Dockerfile
+		}
+		if b.Context == "" {
+			b.Context = "."
+		}
+		return b.Context, dockerfile
+	}
+	return "", dockerfile
+}
+
+// stringList reads a compose field that is either a string or a list of them.
+func stringList(n yaml.Node) []string {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		return []string{n.Value}
+	case yaml.SequenceNode:
+		var list []string
+		if err := n.Decode(&list); err == nil {
+			return list
+		}
+	}
+	return nil
+}
+
+// checkDockerfile checks that every instruction is known and that the first
+// is FROM (ARG may precede it). When the build context is known, local COPY
+// and ADD sources must exist in it.
+func (c *checker) checkDockerfile(path, context string) {
+	lines, err := logicalLines(path, "\\")
+	if err != nil {
+		c.problem(path, "%v", err)
+		return
+	}
+	seenFrom := false
+	for _, l := range lines {
+		fields := strings.Fields(l.text)
+		if len(fields) == 0 {
+			continue
+		}
+		instr := strings.ToUpper(fields[0])
+		if !dockerInstructions[instr] {
+			c.problem(path, "line %d: unknown instruction %s", l.num, fields[0])
+			continue
+		}
+		if !seenFrom && instr != "FROM" && instr != "ARG" {
+			c.problem(path, "line %d: %s before FROM", l.num, instr)
+		}
+		if instr == "FROM" {
+			seenFrom = true
+		}
+		if context == "" || (instr != "COPY" && instr != "ADD") {
+			continue
+		}
+		for _, src := range copySources(fields[1:]) {
+			if matches, _ := filepath.Glob(filepath.Join(context, src)); len(matches) == 0 {
+				c.problem(path, "line %d: %s source %s does not exist in the build context", l.num, instr, src)
+			}
+		}
+	}
+	if !seenFrom {
+		c.problem(path, "no FROM instruction")
+	}
+}
+
+// copySources returns the local sources of a COPY or ADD instruction: its
+// arguments bar flags and the destination. Copies from another build stage
+// and remote URLs have none.
+func copySources(args []string) []string {
+	var srcs []string
+	for _, a := range args {
+		if strings.HasPrefix(a, "--from=") {
+			return nil
+		}
+		if strings.HasPrefix(a, "--") {
+			continue
+		}
+		srcs = append(srcs, a)
+	}
+	if len(srcs) < 2 || strings.HasPrefix(srcs[0], "[") {
+		return nil // malformed or JSON form; docker reports those itself
+	}
+	srcs = srcs[:len(srcs)-1]
+	local := srcs[:0]
+	for _, s := range srcs {
+		if !strings.Contains(s, "://") {
+			local = append(local, s)
+		}
+	}
+	return local
+}
+
+// checkMakefile checks that the Makefile defines a target and that recipe
+// lines are indented with tabs, the mistake make rejects most often.
+func (c *checker) checkMakefile(path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		c.problem(path, "%v", err)
+		return
+	}
+	targets := 0
+	inRecipe := false
+	for i, line := range strings.Split(string(data), "\n") {
+		switch {
+		case strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "#"):
+			continue
+		case strings.HasPrefix(line, "\t"):
+			if !inRecipe {
+				c.problem(path, "line %d: recipe line outside a rule", i+1)
+			}
+		case strings.HasPrefix(line, " ") && inRecipe:
+			c.problem(path, "line %d: recipe indented with spaces instead of a tab", i+1)
+		case isRule(line):
+			targets++
+			inRecipe = true
+		default:
+			inRecipe = false
+		}
+	}
+	if targets == 0 {
+		c.problem(path, "no targets")
+	}
+}
+
+// isRule reports whether a Makefile line starts a rule ("target: deps"), as
+// opposed to a variable assignment.
+func isRule(line string) bool {
+	before, _, ok := strings.Cut(line, ":")
+	if !ok || strings.ContainsAny(before, "=") || strings.HasPrefix(line[len(before):], ":=") {
+		return false
+	}
+	return strings.TrimSpace(before) != ""
+}
+
+// checkEnvTemplate checks that every line is a comment or KEY=value.
+func (c *checker) checkEnvTemplate(path string) {
+	lines, err := logicalLines(path, "")
+	if err != nil {
+		c.problem(path, "%v", err)
+		return
+	}
+	for _, l := range lines {
+		key, _, ok := strings.Cut(l.text, "=")
+		if !ok || !envKey.MatchString(strings.TrimSpace(key)) {
+			c.problem(path, "line %d: want KEY=value", l.num)
+		}
+	}
+}
+
+type line struct {
+	num  int
+	text string
+}
+
+// logicalLines reads the non-blank, non-comment lines of a file, joining
+// lines that end in cont, if set, with the next.
+func logicalLines(path, cont string) ([]line, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []line
+	var cur *line
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		text := strings.TrimSpace(sc.Text())
+		if cur == nil && (text == "" || strings.HasPrefix(text, "#")) {
+			continue
+		}
+		if cur == nil {
+			lines = append(lines, line{num: n})
+			cur = &lines[len(lines)-1]
+		}
+		if cont != "" && strings.HasSuffix(text, cont) {
+			cur.text += strings.TrimSuffix(text, cont) + " "
+			continue
+		}
+		cur.text += text
+		cur = nil
+	}
+	return lines, sc.Err()
+}
@@ -0,0 +1,196 @@
+package devops
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// sound is a deployment config every check passes, relative to the output
+// dir. Cases start from it and override or remove files.
+var sound = map[string]string{
+	"compose.yaml": `services:
+  api:
+    build: ./api
+    env_file: .env.example
+    volumes:
+      - ./data:/data
+  db:
+    image: postgres:16
+`,
+	"api/Dockerfile": "ARG GO=1.24\nFROM golang:${GO}\nWORKDIR /app\nCOPY go.mod main.go ./\nRUN go build \\\n  -o /bin/api .\nCMD [\"/bin/api\"]\n",
+	"api/go.mod":     "module api\n",
+	"api/main.go":    "package main\n",
+	"data/.keep":     "",
+	".env.example":   "# database\nDATABASE_URL=postgres://db/app\nexport PORT=8080\n",
+	"Makefile":       "up:\n\tdocker compose up\n",
+}
+
+// writeTree writes files under dir; a file mapped to nil is left out.
+func writeTree(t *testing.T, dir string, files map[string]*string) {
+	t.Helper()
+	for name, content := range files {
+		if content == nil {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(*content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func str(s string) *string { return &s }
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]*string // overrides of sound; nil removes a file
+		want  []string
+	}{
+		{name: "sound config"},
+
+		// compose
+		{
+			name:  "no compose file",
+			files: map[string]*string{"compose.yaml": nil},
+			want:  []string{"devops: no compose file (want one of compose.yaml, compose.yml, docker-compose.yaml, docker-compose.yml)"},
+		},
+		{
+			name:  "alternate compose name",
+			files: map[string]*string{"compose.yaml": nil, "docker-compose.yml": str("services:\n  web:\n    image: nginx\n")},
+		},
+		{
+			name:  "invalid YAML",
+			files: map[string]*string{"compose.yaml": str("services: [\n")},
+			want:  []string{"devops/compose.yaml: invalid YAML: yaml: line 1: did not find expected node content"},
+		},
+		{
+			name:  "no services",
+			files: map[string]*string{"compose.yaml": str("version: '3'\n")},
+			want:  []string{"devops/compose.yaml: no services"},
+		},
+		{
+			name:  "service with neither build nor image",
+			files: map[string]*string{"compose.yaml": str("services:\n  worker:\n    command: run\n")},
+			want:  []string{"devops/compose.yaml: service worker has neither build nor image"},
+		},
+		{
+			name:  "missing build context",
+			files: map[string]*string{"compose.yaml": str("services:\n  web:\n    build: ./web\n")},
+			want:  []string{"devops/compose.yaml: service web: build context ./web does not exist in the artifacts tree"},
+		},
+		{
+			name:  "build context outside the artifacts tree",
+			files: map[string]*string{"compose.yaml": str("services:\n  web:\n    build: ../../elsewhere\n")},
+			want:  []string{"devops/compose.yaml: service web: build context ../../elsewhere does not exist in the artifacts tree"},
+		},
+		{
+			name: "missing Dockerfile in build context",
+			files: map[string]*string{"compose.yaml": str(`services:
+  api:
+    build:
+      context: ./api
+      dockerfile: prod.Dockerfile
+`)},
+			want: []string{"devops/compose.yaml: service api: Dockerfile prod.Dockerfile does not exist in ./api"},
+		},
+		{
+			name:  "missing env file",
+			files: map[string]*string{".env.example": nil},
+			want:  []string{"devops/compose.yaml: service api: env_file .env.example does not exist"},
+		},
+		{
+			name:  "missing volume source",
+			files: map[string]*string{"data/.keep": nil},
+			want:  []string{"devops/compose.yaml: service api: volume source ./data does not exist"},
+		},
+
+		// Dockerfile
+		{
+			name:  "instruction before FROM",
+			files: map[string]*string{"api/Dockerfile": str("WORKDIR /app\nFROM golang\n")},
+			want:  []string{"devops/api/Dockerfile: line 1: WORKDIR before FROM"},
+		},
+		{
+			name:  "unknown instruction",
+			files: map[string]*string{"api/Dockerfile": str("FROM golang\nINSTALL go\n")},
+			want:  []string{"devops/api/Dockerfile: line 2: unknown instruction INSTALL"},
+		},
+		{
+			name:  "no FROM",
+			files: map[string]*string{"api/Dockerfile": str("ARG GO=1.24\n")},
+			want:  []string{"devops/api/Dockerfile: no FROM instruction"},
+		},
+		{
+			name:  "missing COPY source",
+			files: map[string]*string{"api/Dockerfile": str("FROM golang\nCOPY cmd/ /app/\n")},
+			want:  []string{"devops/api/Dockerfile: line 2: COPY source cmd/ does not exist in the build context"},
+		},
+		{
+			name:  "COPY from another stage",
+			files: map[string]*string{"api/Dockerfile": str("FROM golang AS build\nFROM alpine\nCOPY --from=build /bin/api /bin/api\n")},
+		},
+		{
+			name:  "Dockerfile outside compose is checked without its context",
+			files: map[string]*string{"tools/Dockerfile": str("RUN make\nCOPY nowhere /\n")},
+			want: []string{
+				"devops/tools/Dockerfile: line 1: RUN before FROM",
+				"devops/tools/Dockerfile: line 2: COPY before FROM",
+				"devops/tools/Dockerfile: no FROM instruction",
+			},
+		},
+
+		// env templates
+		{
+			name:  "env line without a value",
+			files: map[string]*string{".env.example": str("DATABASE_URL\n")},
+			want:  []string{"devops/.env.example: line 1: want KEY=value"},
+		},
+		{
+			name:  "env key that isn't an identifier",
+			files: map[string]*string{".env.example": str("# ok\nPORT=8080\n\n1ST-KEY=x\n")},
+			want:  []string{"devops/.env.example: line 4: want KEY=value"},
+		},
+		{
+			name:  "env template by suffix",
+			files: map[string]*string{"api/.env.template": str("not a pair\n")},
+			want:  []string{"devops/api/.env.template: line 1: want KEY=value"},
+		},
+
+		// Makefile
+		{
+			name:  "no Makefile",
+			files: map[string]*string{"Makefile": nil},
+			want:  []string{"devops: no Makefile"},
+		},
+		{
+			name:  "recipe indented with spaces",
+			files: map[string]*string{"Makefile": str("up:\n    docker compose up\n")},
+			want:  []string{"devops/Makefile: line 2: recipe indented with spaces instead of a tab"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			dir := filepath.Join(root, "devops")
+			files := map[string]*string{}
+			for name, content := range sound {
+				files[name] = str(content)
+			}
+			maps.Copy(files, tt.files)
+			writeTree(t, dir, files)
+
+			got := Check(root, dir)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Check() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
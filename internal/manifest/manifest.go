@@ -0,0 +1,167 @@
+// Package manifest records which files a task attempt produced. A snapshot
+// of the task's output dir is taken when the attempt starts and diffed with
+// one taken when it ends; the difference is the attempt's manifest.
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// skipDirs are never snapshotted: VCS metadata and installed dependencies
+// aren't task output and are too big to hash on every attempt.
+var skipDirs = map[string]bool{".git": true, "node_modules": true}
+
+// File is the state of one file in a snapshot.
+type File struct {
+	Size int64  `json:"size"`
+	Hash string `json:"sha256"`
+}
+
+// Snapshot maps each file under a dir, by slash-separated relative path, to
+// its state.
+type Snapshot map[string]File
+
+// Take snapshots the files under dir, bar sentinels. A missing dir gives an
+// empty snapshot.
+func Take(dir string) (Snapshot, error) {
+	s := Snapshot{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && skipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || strings.HasPrefix(d.Name(), ".done") {
+			return nil
+		}
+		f, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		s[filepath.ToSlash(rel)] = f
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", dir, err)
+	}
+	return s, nil
+}
+
+func hashFile(path string) (File, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return File{}, err
+	}
+	defer fh.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, fh)
+	if err != nil {
+		return File{}, err
+	}
+	return File{Size: n, Hash: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// Change is how an attempt changed a file.
+type Change string
+
+const (
+	Added    Change = "added"
+	Modified Change = "modified"
+	Deleted  Change = "deleted"
+)
+
+// Entry is one changed file. Size and hash are the file's state after the
+// attempt, or before it for a deleted file.
+type Entry struct {
+	Path   string `json:"path"`
+	Change Change `json:"change"`
+	File
+}
+
+// Manifest lists the files one attempt of a task changed in its output dir.
+// Tasks sharing an output dir and running at the same time see each other's
+// changes; the orchestrator records such overlaps on the attempt.
+type Manifest struct {
+	Task      string  `json:"task"`
+	Attempt   int     `json:"attempt"`
+	OutputDir string  `json:"output_dir"` // relative to the artifacts root
+	Files     []Entry `json:"files"`
+}
+
+// Diff returns the changes from before to after, sorted by path.
+func Diff(before, after Snapshot) []Entry {
+	var entries []Entry
+	for p, f := range after {
+		old, ok := before[p]
+		switch {
+		case !ok:
+			entries = append(entries, Entry{Path: p, Change: Added, File: f})
+		case old != f:
+			entries = append(entries, Entry{Path: p, Change: Modified, File: f})
+		}
+	}
+	for p, f := range before {
+		if _, ok := after[p]; !ok {
+			entries = append(entries, Entry{Path: p, Change: Deleted, File: f})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries
+}
+
+// Written returns the paths the attempt added or modified, in order.
+func (m *Manifest) Written() []string {
+	var paths []string
+	for _, e := range m.Files {
+		if e.Change != Deleted {
+			paths = append(paths, e.Path)
+		}
+	}
+	return paths
+}
+
+// Save writes v, a snapshot or manifest, to path as JSON.
+func Save(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("mkdir %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadSnapshot reads a snapshot written by Save.
+func LoadSnapshot(path string) (Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return s, nil
+}
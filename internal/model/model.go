@@ -1,13 +1,27 @@
 package model
 
+import "strings"
+
 type TaskStatus string
 
 const (
 	StatusPending   TaskStatus = "pending"
+	StatusLaunching TaskStatus = "launching" // agent window starting, not yet accepting input
 	StatusRunning   TaskStatus = "running"
 	StatusCompleted TaskStatus = "completed"
 	StatusFailed    TaskStatus = "failed"
 	StatusRejected  TaskStatus = "rejected"
+	StatusCancelled TaskStatus = "cancelled" // stopped by the user or a shutdown; re-queued on resume
+	StatusFixing    TaskStatus = "fixing"    // failed verification; a fixer is repairing the output
+
+	// StatusAwaitingApproval holds a reviewed task until a human signs off.
+	StatusAwaitingApproval TaskStatus = "awaiting_approval"
+
+	// StatusBlocked marks a task that can't run because a task it depends on,
+	// directly or transitively, failed. It returns to pending if that task is retried.
+	StatusBlocked TaskStatus = "blocked"
+	// StatusSkipped marks a blocked task the run gave up on.
+	StatusSkipped TaskStatus = "skipped"
 )
 
 type AgentRole string
@@ -19,9 +33,13 @@ const (
 	RoleDatabase   AgentRole = "database"
 	RoleMigrator   AgentRole = "migrator"
 	RoleReviewer   AgentRole = "reviewer"
+	RoleTester     AgentRole = "tester"
+	RoleFixer      AgentRole = "fixer"
+	RoleDevops     AgentRole = "devops"
 	RoleIntegrator AgentRole = "integrator"
 )
 
+// MaxAttempts is the default per-class failure budget of a task.
 const MaxAttempts = 3
 
 type Task struct {
@@ -35,8 +53,133 @@ type Task struct {
 	Result       string     `json:"result,omitempty"`
 	Error        string     `json:"error,omitempty"`
 	Feedback     string     `json:"feedback,omitempty"`
-	Attempts     int        `json:"attempts"`
+	Attempts     int        `json:"attempts"` // review rejections of the current output
 	ReviewTaskID string     `json:"review_task_id,omitempty"`
 	PaneID       string     `json:"pane_id,omitempty"`
 	StartedAt    int64      `json:"started_at,omitempty"`
+	FinishedAt   int64      `json:"finished_at,omitempty"`
+	Priority     int        `json:"priority,omitempty"` // higher launches first among ready tasks
+
+	BlockedBy string `json:"blocked_by,omitempty"` // failed task keeping this one blocked or skipped
+
+	Retry       *RetryPolicy         `json:"retry,omitempty"`        // overrides DefaultRetryPolicy
+	Failures    map[FailureClass]int `json:"failures,omitempty"`     // failed attempts per class, bar review rejections
+	LastFailure FailureClass         `json:"last_failure,omitempty"` // class of the most recent failure
+	RetryAfter  int64                `json:"retry_after,omitempty"`  // unix time before which a retry won't launch
+
+	RequiresApproval bool `json:"requires_approval,omitempty"`
+	Approved         bool `json:"approved,omitempty"` // human sign-off for the current output
+
+	History []Attempt `json:"history,omitempty"` // every launch of this task, oldest first
+
+	// A code task reviewed by a panel lists its reviewer tasks here instead
+	// of in ReviewTaskID; their verdicts combine under Consensus.
+	Panel     []string  `json:"panel,omitempty"`
+	Consensus Consensus `json:"consensus,omitempty"`
+	Lens      string    `json:"lens,omitempty"`   // a panel reviewer's focus, e.g. "security"
+	Lenses    []string  `json:"lenses,omitempty"` // every lens on a panel reviewer's panel
+
+	// TestTaskID links a code task to the tester that tests it, and a tester
+	// back to the task it tests.
+	TestTaskID string `json:"test_task_id,omitempty"`
+
+	// FixTaskID links a task failing verification to the fixer repairing
+	// it, and a fixer back to the task it fixes. Files lists what the fixer
+	// may edit, relative to its output dir.
+	FixTaskID string   `json:"fix_task_id,omitempty"`
+	Files     []string `json:"files,omitempty"`
+
+	Children []string `json:"children,omitempty"` // tasks spliced in from this task's sub-plan
+
+	CacheKey      string `json:"cache_key,omitempty"`      // hash of the task's inputs; see orchestrator.cacheKey
+	CachedAttempt int    `json:"cached_attempt,omitempty"` // attempt whose output was last stored under CacheKey
+
+	Paths []string `json:"paths,omitempty"` // target repo paths a brownfield task changes
+
+	// Changed lists the files the task a reviewer reviews has written,
+	// relative to the artifacts root. It is set as the reviewer launches and
+	// scopes what it is shown.
+	Changed []string `json:"changed,omitempty"`
+}
+
+// Consensus decides how the verdicts of a task's reviewers combine.
+type Consensus string
+
+const (
+	ConsensusAll      Consensus = "all"      // every reviewer must approve (the default)
+	ConsensusMajority Consensus = "majority" // more than half must approve
+	ConsensusBlocking Consensus = "blocking" // rejected only if a reviewer raises a [blocking] issue
+)
+
+// Reviewers returns the IDs of the tasks reviewing t: its panel, or its
+// single reviewer. It is empty for reviewer tasks themselves.
+func (t *Task) Reviewers() []string {
+	switch {
+	case t.Role == RoleReviewer:
+		return nil
+	case len(t.Panel) > 0:
+		return t.Panel
+	case t.ReviewTaskID != "":
+		return []string{t.ReviewTaskID}
+	}
+	return nil
+}
+
+// Attempt records one launch of a task's agent and how it turned out.
+type Attempt struct {
+	Number     int    `json:"number"`
+	StartedAt  int64  `json:"started_at"`
+	FinishedAt int64  `json:"finished_at,omitempty"`
+	PaneID     string `json:"pane_id,omitempty"`
+	PromptFile string `json:"prompt_file,omitempty"`
+
+	// Completion says how the attempt ended: "sentinel" or "verified" when the
+	// agent finished, a FailureClass, "cancelled", or "retried".
+	Completion string `json:"completion,omitempty"`
+	Error      string `json:"error,omitempty"`
+
+	Verdict  string `json:"verdict,omitempty"` // e.g. "approved by review-api", "rejected by human"
+	Feedback string `json:"feedback,omitempty"`
+
+	FilesChanged []string `json:"files_changed,omitempty"` // relative to the task's output dir
+	Manifest     string   `json:"manifest,omitempty"`      // path of the attempt's manifest
+
+	// Overlapped lists the tasks that had an attempt running in the same
+	// output dir at the same time. FilesChanged may include their files, so
+	// it doesn't prove which files this attempt wrote.
+	Overlapped []string `json:"overlapped,omitempty"`
+}
+
+// VerdictFailedVerification is the verdict of an attempt whose verify
+// command failed; rejections read "rejected by <who>".
+const VerdictFailedVerification = "failed verification"
+
+// FeedbackRound is one round of rework feedback on a task.
+type FeedbackRound struct {
+	Attempt  int    // attempt the feedback was about; 0 if given before any
+	By       string // reviewer task, "architect-validate", "human", "verify command" or "user"
+	Feedback string
+}
+
+// FeedbackRounds returns every round of rework feedback on t, oldest first:
+// rejections and verification failures from its history, then any guidance
+// given with a manual retry that no attempt has answered yet.
+func (t *Task) FeedbackRounds() []FeedbackRound {
+	var rounds []FeedbackRound
+	for _, a := range t.History {
+		if a.Feedback == "" {
+			continue
+		}
+		by, rejected := strings.CutPrefix(a.Verdict, "rejected by ")
+		if a.Verdict == VerdictFailedVerification {
+			by, rejected = "verify command", true
+		}
+		if rejected {
+			rounds = append(rounds, FeedbackRound{Attempt: a.Number, By: by, Feedback: a.Feedback})
+		}
+	}
+	if t.Feedback != "" && (len(rounds) == 0 || rounds[len(rounds)-1].Feedback != t.Feedback) {
+		rounds = append(rounds, FeedbackRound{Attempt: len(t.History), By: "user", Feedback: t.Feedback})
+	}
+	return rounds
 }
@@ -0,0 +1,107 @@
+package model
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// FailureClass says why an attempt at a task failed. Each class has its own
+// retry budget, so infrastructure trouble doesn't use up review rejections.
+type FailureClass string
+
+const (
+	FailLaunchError     FailureClass = "launch_error"         // tmux or the agent TUI didn't start
+	FailAgentCrash      FailureClass = "agent_crash"          // pane exited without writing its sentinel
+	FailTimeout         FailureClass = "timeout"              // ran longer than its role's timeout
+	FailVerification    FailureClass = "verification_failure" // the role's verify command failed
+	FailReviewRejection FailureClass = "review_rejection"     // reviewer, architect or human rejected it
+)
+
+// FailureClasses lists every failure class.
+var FailureClasses = []FailureClass{FailLaunchError, FailAgentCrash, FailTimeout, FailVerification, FailReviewRejection}
+
+// Transient reports whether the class is an infrastructure failure, where
+// the agent never got to finish. Only these are retried with backoff.
+func (c FailureClass) Transient() bool {
+	return c == FailLaunchError || c == FailAgentCrash || c == FailTimeout
+}
+
+// RetryPolicy says how a task is retried after a failed attempt. Zero fields
+// inherit from the policy it overrides (see With).
+type RetryPolicy struct {
+	// MaxAttempts is how many failures of one class a task may have before it
+	// fails for good. Every class has its own count.
+	MaxAttempts int `json:"max_attempts,omitempty" yaml:"max_attempts"`
+
+	// Backoff delays the first retry after a transient failure; each further
+	// retry of the same class waits twice as long, up to MaxBackoff.
+	Backoff time.Duration `json:"backoff,omitempty" yaml:"backoff"`
+
+	// RetryOn lists the classes retried automatically. Any other class fails
+	// the task on its first occurrence.
+	RetryOn []FailureClass `json:"retry_on,omitempty" yaml:"retry_on"`
+}
+
+// MaxBackoff caps the delay between retries.
+const MaxBackoff = 5 * time.Minute
+
+// DefaultRetryPolicy applies where neither the config nor the task plan sets one.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: MaxAttempts,
+	Backoff:     10 * time.Second,
+	RetryOn:     FailureClasses,
+}
+
+// With returns p with every field set in over replacing its own.
+func (p RetryPolicy) With(over *RetryPolicy) RetryPolicy {
+	if over == nil {
+		return p
+	}
+	if over.MaxAttempts > 0 {
+		p.MaxAttempts = over.MaxAttempts
+	}
+	if over.Backoff > 0 {
+		p.Backoff = over.Backoff
+	}
+	if over.RetryOn != nil {
+		p.RetryOn = over.RetryOn
+	}
+	return p
+}
+
+// Validate rejects a policy naming an unknown failure class. A nil policy is valid.
+func (p *RetryPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	for _, c := range p.RetryOn {
+		if !slices.Contains(FailureClasses, c) {
+			return fmt.Errorf("unknown failure class %q", c)
+		}
+	}
+	return nil
+}
+
+// Retries reports whether failures of class are retried at all.
+func (p RetryPolicy) Retries(class FailureClass) bool {
+	return slices.Contains(p.RetryOn, class)
+}
+
+// Delay returns how long to wait before retrying after the n-th failure of
+// class (n starts at 1).
+func (p RetryPolicy) Delay(class FailureClass, n int) time.Duration {
+	if !class.Transient() || p.Backoff <= 0 {
+		return 0
+	}
+	d := p.Backoff
+	for i := 1; i < n && d < MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, MaxBackoff)
+}
+
+// RetryPolicy returns the policy in effect for t.
+func (t *Task) RetryPolicy() RetryPolicy {
+	return DefaultRetryPolicy.With(t.Retry)
+}
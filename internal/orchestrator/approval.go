@@ -0,0 +1,76 @@
+package orchestrator
+
+import (
+	"fmt"
+
+	"github.com/hubenschmidt/claude-dag/internal/model"
+)
+
+// holdForApproval parks a completed task until a human approves or rejects it.
+func (o *Orchestrator) holdForApproval(id string) {
+	if o.graph.Transition(id, model.StatusCompleted, model.StatusAwaitingApproval) {
+		o.logEvent("awaiting human approval: %s", id)
+	}
+}
+
+// requestApprovals holds completed tasks that need sign-off but have no
+// reviewer; reviewed tasks are held by processReviews once approved.
+func (o *Orchestrator) requestApprovals() {
+	for _, t := range o.graph.Tasks() {
+		if !t.RequiresApproval || t.Approved || len(t.Reviewers()) != 0 {
+			continue
+		}
+		if t.Status == model.StatusCompleted {
+			o.holdForApproval(t.ID)
+		}
+	}
+}
+
+// pendingApprovals returns the tasks waiting for a human decision.
+func (o *Orchestrator) pendingApprovals() []*model.Task {
+	var pending []*model.Task
+	for _, t := range o.graph.Tasks() {
+		if t.Status == model.StatusAwaitingApproval {
+			pending = append(pending, t)
+		}
+	}
+	return pending
+}
+
+// approveTask signs off on a task awaiting approval.
+func (o *Orchestrator) approveTask(id string) (string, error) {
+	if err := o.graph.Approve(id); err != nil {
+		return "", err
+	}
+	_ = o.graph.SetAttemptVerdict(id, "approved by human", "")
+	o.logEvent("human APPROVED: %s", id)
+	return fmt.Sprintf("approved %s", id), nil
+}
+
+// rejectTask sends a task awaiting approval back for rework with the human's
+// feedback, through the same path as a reviewer rejection.
+func (o *Orchestrator) rejectTask(id, feedback string) (string, error) {
+	if feedback == "" {
+		return "", fmt.Errorf("reject needs feedback for the agent")
+	}
+	if !o.graph.Transition(id, model.StatusAwaitingApproval, model.StatusRejected) {
+		return "", fmt.Errorf("task %s is not awaiting approval", id)
+	}
+	o.sendBack(id, "human", feedback)
+	o.logEvent("human REJECTED: %s", id)
+	return fmt.Sprintf("rejected %s", id), nil
+}
+
+// printApprovals lists tasks waiting for sign-off on the dashboard.
+func (o *Orchestrator) printApprovals() {
+	pending := o.pendingApprovals()
+	if len(pending) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Println("--- Pending Approvals ---")
+	for _, t := range pending {
+		fmt.Printf("  %s (%s): %s\n", t.ID, t.Role, t.Description)
+	}
+	fmt.Println("  approve: swarm ctl approve <task>   reject: swarm ctl reject <task> <feedback>")
+}
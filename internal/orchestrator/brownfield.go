@@ -0,0 +1,73 @@
+package orchestrator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/hubenschmidt/claude-dag/internal/artifact"
+	"github.com/hubenschmidt/claude-dag/internal/model"
+)
+
+// repoMapDir holds the structural map of the target repository the architect
+// designs against, artifacts/repomap/repo-map.md.
+const repoMapDir = "repomap"
+
+// builderRoles write code, so in brownfield mode they change the target
+// repository rather than a fresh tree under artifacts/code/.
+var builderRoles = map[model.AgentRole]bool{
+	model.RoleBackend:    true,
+	model.RoleFrontend:   true,
+	model.RoleDatabase:   true,
+	model.RoleMigrator:   true,
+	model.RoleIntegrator: true,
+}
+
+// SetBrownfield makes builders change the target repository checked out at
+// artifacts/repo/ from commit base instead of writing greenfield code, and has
+// the architect design against the repository's map.
+func (o *Orchestrator) SetBrownfield(base string) {
+	o.brownfield = true
+	o.baseCommit = base
+}
+
+// targetRepo points a builder task at the target repository: its output dir
+// is the worktree, and it reads the repo paths it changes. Paths must be
+// relative and stay inside the repository; they needn't exist yet.
+func targetRepo(t *model.Task, paths []string) error {
+	for _, p := range paths {
+		clean := filepath.Clean(p)
+		if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("path %q is outside the repository", p)
+		}
+		t.Paths = append(t.Paths, clean)
+	}
+	t.OutputDir = artifact.RepoDir
+	t.ArtifactDirs = append(slices.DeleteFunc(t.ArtifactDirs, func(d string) bool {
+		return strings.HasPrefix(d, "code/")
+	}), scopeDirs(t)...)
+	return nil
+}
+
+// scopeDirs returns the artifact dirs holding a code task's output: the
+// repo dirs it changes, or else its whole output dir. A path that isn't an
+// existing directory, such as a file or a package yet to be written, stands
+// for its parent.
+func scopeDirs(t *model.Task) []string {
+	if len(t.Paths) == 0 {
+		return []string{t.OutputDir}
+	}
+	var dirs []string
+	for _, p := range t.Paths {
+		dir := filepath.Join(artifact.RepoDir, p)
+		if info, err := os.Stat(filepath.Join(artifact.BaseDir, dir)); err != nil || !info.IsDir() {
+			dir = filepath.Join(artifact.RepoDir, filepath.Dir(p))
+		}
+		if !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
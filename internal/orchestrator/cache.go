@@ -0,0 +1,205 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hubenschmidt/claude-dag/internal/agent"
+	"github.com/hubenschmidt/claude-dag/internal/artifact"
+	"github.com/hubenschmidt/claude-dag/internal/cache"
+	"github.com/hubenschmidt/claude-dag/internal/model"
+)
+
+// SetCache memoizes approved task outputs in c, reusing them when a task's
+// inputs match an earlier one. A nil cache disables memoization.
+func (o *Orchestrator) SetCache(c *cache.Cache) {
+	o.cache = c
+}
+
+// launchReady completes ready tasks whose outputs are cached, then launches
+// agents for the rest.
+func (o *Orchestrator) launchReady() error {
+	o.restoreCached()
+	return o.dispatcher.LaunchReady(o.graph)
+}
+
+// cacheKey hashes everything that goes into a task: its role, description,
+// output dir, review lens and repo paths, its prompt template, the model and
+// the role's config, the commit a brownfield run started from, and the
+// content of every artifact dir it reads.
+func (o *Orchestrator) cacheKey(t *model.Task) (string, error) {
+	k := cache.NewKey()
+	k.Add("role", string(t.Role))
+	k.Add("description", t.Description)
+	k.Add("output", t.OutputDir)
+	k.Add("paths", strings.Join(t.Paths, "\n"))
+	k.Add("base", o.baseCommit)
+	k.Add("model", os.Getenv("ANTHROPIC_MODEL"))
+
+	rc, err := json.Marshal(o.config.Role(t.Role))
+	if err != nil {
+		return "", err
+	}
+	k.Add("config", string(rc))
+
+	prompt, err := agent.LoadPrompt(string(t.Role))
+	if err != nil {
+		return "", err
+	}
+	k.Add("prompt", prompt)
+	if t.Lens != "" {
+		lens, err := agent.LoadPrompt(lensPrompt(t.Lens))
+		if err != nil {
+			return "", err
+		}
+		k.Add("lens", lens)
+		k.Add("panel", strings.Join(t.Lenses, "\n"))
+	}
+
+	for _, dir := range t.ArtifactDirs {
+		if err := k.AddDir("input/"+dir, filepath.Join(artifact.BaseDir, dir)); err != nil {
+			return "", err
+		}
+	}
+	return k.Sum(), nil
+}
+
+// restoreCached keys every ready task about to make its first attempt and,
+// on a cache hit, restores the cached outputs and completes it without
+// launching an agent. Tasks given feedback up front are never restored.
+func (o *Orchestrator) restoreCached() {
+	if o.cache == nil {
+		return
+	}
+	for _, t := range o.graph.ReadyTasks() {
+		if t.CacheKey != "" || t.Feedback != "" || len(t.History) > 0 {
+			continue
+		}
+		key, err := o.cacheKey(t)
+		if err != nil {
+			log.Printf("[cache] key for %s: %v", t.ID, err)
+			continue
+		}
+		_ = o.graph.SetCacheKey(t.ID, key)
+		if !o.cache.Has(key) {
+			continue
+		}
+
+		e, err := o.cache.Restore(key, filepath.Join(artifact.BaseDir, t.OutputDir))
+		if err != nil {
+			log.Printf("[cache] %s: %v", t.ID, err)
+			continue
+		}
+		if !o.graph.Transition(t.ID, model.StatusPending, model.StatusCompleted) {
+			continue
+		}
+		now := time.Now()
+		_ = o.graph.StartAttempt(t.ID, now)
+		_ = o.graph.EndAttempt(t.ID, "cached", "", now, e.Files)
+		_ = o.graph.SetCachedAttempt(t.ID, 1)
+		_ = o.graph.SetStartedAt(t.ID, now.Unix())
+		_ = o.graph.SetFinishedAt(t.ID, now.Unix())
+		o.logEvent("task %s completed (cached from %s)", t.ID, e.Task)
+	}
+}
+
+// storeApproved caches the outputs of tasks that have been approved since
+// they were last stored, replacing the entry under the key computed before
+// their first attempt. Nothing is stored until the plan is accepted.
+func (o *Orchestrator) storeApproved() {
+	if o.cache == nil || !o.isPlanAccepted() {
+		return
+	}
+	tasks := o.graph.Tasks()
+	for _, t := range tasks {
+		// Which files are t's depends on every task writing to its dir.
+		if slices.ContainsFunc(tasks, func(u *model.Task) bool { return u.OutputDir == t.OutputDir && o.filesPending(u.ID) }) {
+			continue
+		}
+		n := len(t.History)
+		if t.CacheKey == "" || n == 0 || t.CachedAttempt == t.History[n-1].Number || !o.approvedOutput(t) {
+			continue
+		}
+		files := ownedFiles(t, tasks)
+		if len(files) == 0 {
+			continue
+		}
+		e := cache.Entry{Task: t.ID, Role: string(t.Role), Files: files, StoredAt: time.Now()}
+		if err := o.cache.Store(t.CacheKey, filepath.Join(artifact.BaseDir, t.OutputDir), e); err != nil {
+			log.Printf("[cache] %s: %v", t.ID, err)
+			continue
+		}
+		_ = o.graph.SetCachedAttempt(t.ID, t.History[n-1].Number)
+		log.Printf("[cache] stored %d file(s) of %s", len(files), t.ID)
+	}
+}
+
+// ownedFiles returns the files under t's output dir that t, or a fixer of
+// t, wrote and that still exist. Output dirs are shared, so a file some other
+// task also wrote there isn't provably t's and is left out: restoring it
+// would clobber that task's output. If an attempt of t overlapped another
+// task's, none of its files is provably t's, and ownedFiles returns nil.
+func ownedFiles(t *model.Task, tasks []*model.Task) []string {
+	overlapped := false
+	written := func(w *model.Task) []string {
+		var files []string
+		for _, a := range w.History {
+			files = append(files, a.FilesChanged...)
+		}
+		return files
+	}
+
+	var files []string
+	claimed := map[string]bool{}
+	for _, w := range tasks {
+		if w.OutputDir != t.OutputDir {
+			continue
+		}
+		mine := w.ID == t.ID || (w.Role == model.RoleFixer && w.FixTaskID == t.ID)
+		if mine && slices.ContainsFunc(w.History, func(a model.Attempt) bool { return len(a.Overlapped) > 0 }) {
+			overlapped = true
+		}
+		for _, f := range written(w) {
+			switch {
+			case !mine:
+				claimed[f] = true
+			case !slices.Contains(files, f):
+				files = append(files, f)
+			}
+		}
+	}
+	if overlapped {
+		return nil
+	}
+	return slices.DeleteFunc(files, func(f string) bool {
+		if claimed[f] {
+			return true
+		}
+		_, err := os.Stat(filepath.Join(artifact.BaseDir, t.OutputDir, f))
+		return err != nil
+	})
+}
+
+// approvedOutput reports whether t's output is final: completed, approved by
+// its reviewers, passed by its tester, and signed off if it needs to be.
+// Architect validation counts only once it approves. Fixers and tasks that
+// split into a sub-plan aren't cached.
+func (o *Orchestrator) approvedOutput(t *model.Task) bool {
+	if t.Status != model.StatusCompleted || t.Role == model.RoleFixer || len(t.Children) > 0 {
+		return false
+	}
+	if t.ID == "architect-validate" {
+		report, err := artifact.Read("reviews", "architect-validate.md")
+		if err != nil {
+			return false
+		}
+		verdict, _ := parseVerdict(report)
+		return verdict == "APPROVED"
+	}
+	return o.graph.Accepted(t.ID)
+}
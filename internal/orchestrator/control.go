@@ -0,0 +1,124 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hubenschmidt/claude-dag/internal/control"
+	"github.com/hubenschmidt/claude-dag/internal/tmux"
+)
+
+// HandleControl executes a command received on the control socket. It runs
+// on the socket's goroutine, so every change goes through Graph or Dispatcher
+// locking rather than touching tasks directly.
+func (o *Orchestrator) HandleControl(req control.Request) (string, error) {
+	// Whatever changed, let the poll loop act on it now.
+	defer o.notify()
+
+	switch req.Command {
+	case control.CmdPause:
+		o.dispatcher.SetPaused(true)
+		o.logEvent("ctl: dispatching paused")
+		return "dispatching paused", nil
+
+	case control.CmdResume:
+		o.dispatcher.SetPaused(false)
+		o.logEvent("ctl: dispatching resumed")
+		return "dispatching resumed", nil
+
+	case control.CmdRetry:
+		return o.ctlRetry(req.Task, req.Feedback)
+
+	case control.CmdCancel:
+		return o.ctlCancel(req.Task)
+
+	case control.CmdConcurrency:
+		if err := o.dispatcher.SetMaxConcurrent(req.Value); err != nil {
+			return "", err
+		}
+		o.logEvent("ctl: concurrency set to %d", req.Value)
+		return fmt.Sprintf("concurrency set to %d", req.Value), nil
+
+	case control.CmdAdd:
+		return o.ctlAdd(req)
+
+	case control.CmdApprove:
+		return o.approveTask(req.Task)
+
+	case control.CmdReject:
+		return o.rejectTask(req.Task, req.Feedback)
+
+	default:
+		return "", fmt.Errorf("unknown command %q", req.Command)
+	}
+}
+
+// ctlRetry re-queues a task with feedback, killing its pane if it is still
+// running so the new attempt starts clean. The status changes before the pane
+// dies so reapFinished never mistakes the kill for a completion.
+func (o *Orchestrator) ctlRetry(id, feedback string) (string, error) {
+	paneID, err := o.graph.RunningPane(id)
+	if err != nil {
+		return "", err
+	}
+	o.endAttempt(id, "retried", "")
+	if err := RetryTask(o.graph, id, feedback); err != nil {
+		return "", err
+	}
+	o.killPane(id, paneID)
+	o.logEvent("ctl: retry %s", id)
+	return fmt.Sprintf("re-queued %s", id), nil
+}
+
+// ctlCancel marks a pending or active task cancelled so it is not
+// relaunched, then kills its pane. The status changes first, in one step, so
+// the poll loop can't complete the task in between.
+func (o *Orchestrator) ctlCancel(id string) (string, error) {
+	paneID, err := o.graph.Cancel(id)
+	if err != nil {
+		return "", err
+	}
+	_ = o.graph.SetError(id, "cancelled via control socket")
+	o.endAttempt(id, "cancelled", "cancelled via control socket")
+	o.killPane(id, paneID)
+	o.logEvent("ctl: cancelled %s", id)
+	return fmt.Sprintf("cancelled %s", id), nil
+}
+
+// ctlAdd adds a task as if it had appeared in the task plan. Until the plan
+// is accepted the graph still belongs to plan expansion and review, so adding
+// is refused.
+func (o *Orchestrator) ctlAdd(req control.Request) (string, error) {
+	if req.Task == "" || req.Description == "" {
+		return "", fmt.Errorf("add needs a task ID and a description")
+	}
+	if !o.isPlanAccepted() {
+		return "", fmt.Errorf("tasks can be added once the plan is accepted")
+	}
+	role, ok := planRoles[strings.ToLower(req.Role)]
+	if !ok || !o.dispatcher.HasAgent(role) {
+		return "", fmt.Errorf("no agent for role %q", req.Role)
+	}
+
+	entry := taskPlanEntry{
+		ID:               req.Task,
+		Role:             req.Role,
+		Description:      req.Description,
+		DependsOn:        req.DependsOn,
+		RequiresApproval: req.RequiresApproval,
+	}
+	if err := o.addPlanEntry(entry, "architect-design"); err != nil {
+		return "", err
+	}
+	o.logEvent("ctl: added %s (%s)", req.Task, role)
+	return fmt.Sprintf("added %s", req.Task), nil
+}
+
+// killPane terminates a task's old pane, if any.
+func (o *Orchestrator) killPane(id, paneID string) {
+	if paneID == "" {
+		return
+	}
+	_ = tmux.KillPane(paneID)
+	_ = o.graph.SetPaneID(id, "")
+}
@@ -0,0 +1,63 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hubenschmidt/claude-dag/internal/artifact"
+	"github.com/hubenschmidt/claude-dag/internal/devops"
+	"github.com/hubenschmidt/claude-dag/internal/model"
+)
+
+// runDevops spawns the devops agent once the code is validated and
+// integrated, to produce the container and run configuration for it.
+func (o *Orchestrator) runDevops(ctx context.Context) error {
+	if _, ok := o.graph.Get("devops"); !ok {
+		deps := []string{"architect-validate"}
+		for _, t := range o.graph.Tasks() {
+			if t.Role == model.RoleIntegrator {
+				deps = append(deps, t.ID)
+			}
+		}
+		task := &model.Task{
+			ID:           "devops",
+			Role:         model.RoleDevops,
+			Description:  "Produce Dockerfiles, a compose file, Makefile targets and environment templates to run the project",
+			DependsOn:    deps,
+			ArtifactDirs: artifactDirsForRole(model.RoleDevops),
+			OutputDir:    outputDirForRole(model.RoleDevops),
+		}
+		if err := o.addTask(task); err != nil {
+			return fmt.Errorf("add devops task: %w", err)
+		}
+	}
+	if err := o.launchReady(); err != nil {
+		return fmt.Errorf("launch devops: %w", err)
+	}
+	return o.pollUntilDone(ctx, "devops")
+}
+
+// checkDevops checks the configuration a devops task produced once its
+// agent has finished. Files that don't parse or reference missing paths fail
+// the attempt as a verification failure, with the problems as feedback. It
+// reports whether the check passed.
+//
+// Unlike a failing verify command, problems aren't handed to a fixer: most
+// are missing files (a Makefile, a COPY source, a build context) that a
+// fixer, confined to editing files that exist, couldn't create. The devops
+// agent reworks the whole config instead.
+func (o *Orchestrator) checkDevops(t *model.Task) bool {
+	root, err := filepath.Abs(artifact.BaseDir)
+	if err != nil {
+		root = artifact.BaseDir
+	}
+	problems := devops.Check(root, filepath.Join(root, t.OutputDir))
+	if len(problems) == 0 {
+		return true
+	}
+	detail := fmt.Sprintf("Deployment config check found %d problem(s):\n%s", len(problems), strings.Join(problems, "\n"))
+	o.failTask(t.ID, model.StatusRunning, model.FailVerification, detail)
+	return false
+}
@@ -3,21 +3,31 @@ package orchestrator
 import (
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/hubenschmidt/claude-dag/internal/agent"
 	"github.com/hubenschmidt/claude-dag/internal/model"
+	"github.com/hubenschmidt/claude-dag/internal/tmux"
 )
 
-const (
-	maxConcurrent = 4
-	staggerDelay  = 3 * time.Second // delay between launches so each TUI can initialize
-)
+const defaultMaxConcurrent = 4
 
 // Dispatcher maps agent roles to agent instances and launches them in tmux panes.
 type Dispatcher struct {
 	agents  map[model.AgentRole]agent.Agent
 	session string
+	sched   *scheduler
+
+	mu            sync.Mutex
+	paused        bool
+	stopped       bool // set by Stop; unlike paused, never cleared
+	maxConcurrent int
+	inflight      map[string]bool // tasks whose launch goroutine hasn't returned
+
+	onStart        func(id string)            // called in the launch goroutine, before the agent launches
+	onLaunched     func()                     // called when a background launch settles, if set
+	onLaunchFailed func(id string, err error) // records a launch error; the task is still launching
 }
 
 // NewDispatcher creates a dispatcher bound to the given tmux session.
@@ -26,13 +36,69 @@ func NewDispatcher(session string, agents []agent.Agent) *Dispatcher {
 	for _, a := range agents {
 		m[a.Role()] = a
 	}
-	return &Dispatcher{agents: m, session: session}
+	return &Dispatcher{agents: m, session: session, sched: newScheduler(), maxConcurrent: defaultMaxConcurrent, inflight: map[string]bool{}}
+}
+
+// SetPaused stops (or resumes) launching new tasks. Running tasks are unaffected.
+func (d *Dispatcher) SetPaused(paused bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.paused = paused
+}
+
+// Stop permanently ends launching, for shutdown. Tasks already launching
+// still settle in the background.
+func (d *Dispatcher) Stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopped = true
+}
+
+// Stopped reports whether Stop was called.
+func (d *Dispatcher) Stopped() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stopped
+}
+
+// Paused reports whether launching is paused.
+func (d *Dispatcher) Paused() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.paused
+}
+
+// SetMaxConcurrent changes the cap on simultaneously running tasks.
+func (d *Dispatcher) SetMaxConcurrent(n int) error {
+	if n < 1 {
+		return fmt.Errorf("concurrency must be at least 1, got %d", n)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.maxConcurrent = n
+	return nil
+}
+
+// HasAgent reports whether a role can be dispatched.
+func (d *Dispatcher) HasAgent(role model.AgentRole) bool {
+	_, ok := d.agents[role]
+	return ok
 }
 
-// LaunchReady finds pending-and-ready tasks, launching up to maxConcurrent
-// total running tasks. Staggers launches so each Claude TUI has time to init.
+// LaunchReady finds pending-and-ready tasks and starts launching up to
+// maxConcurrent total active tasks in scheduler order. Launches run in the
+// background: each task is marked launching immediately and becomes running
+// (or failed) once its agent's TUI has accepted the prompt, so the poll loop
+// never blocks on tmux.
 func (d *Dispatcher) LaunchReady(g *Graph) error {
-	slots := maxConcurrent - g.RunningCount()
+	d.mu.Lock()
+	paused, limit := d.paused || d.stopped, d.maxConcurrent
+	d.mu.Unlock()
+	if paused {
+		return nil
+	}
+
+	slots := limit - g.RunningCount()
 	if slots <= 0 {
 		return nil
 	}
@@ -42,45 +108,83 @@ func (d *Dispatcher) LaunchReady(g *Graph) error {
 		return nil
 	}
 
-	if len(ready) > slots {
-		ready = ready[:slots]
+	decisions := d.sched.order(g, ready)
+	if len(decisions) > slots {
+		for _, skipped := range decisions[slots:] {
+			log.Printf("[schedule] defer %s: %s", skipped.task.ID, skipped.reason)
+		}
+		decisions = decisions[:slots]
 	}
 
-	log.Printf("[dispatch] launching %d task(s) (%d slots available)", len(ready), slots)
+	log.Printf("[dispatch] launching %d task(s) (%d slots available)", len(decisions), slots)
 
-	for i, task := range ready {
-		// Stagger after the first launch so each TUI can start before the next
-		if i > 0 {
-			time.Sleep(staggerDelay)
-		}
-		if err := d.launchTask(g, task); err != nil {
-			return err
-		}
+	for _, dec := range decisions {
+		log.Printf("[schedule] launch %s: %s", dec.task.ID, dec.reason)
+		d.launchTask(g, dec.task.ID)
 	}
 	return nil
 }
 
-func (d *Dispatcher) launchTask(g *Graph, task *model.Task) error {
+// launchTask claims a pending task and launches its agent in the background.
+// A task re-queued while its previous launch is still in flight waits for
+// that launch to finish so the two can't be confused.
+func (d *Dispatcher) launchTask(g *Graph, id string) {
+	d.mu.Lock()
+	busy := d.inflight[id]
+	d.mu.Unlock()
+	if busy || !g.Transition(id, model.StatusPending, model.StatusLaunching) {
+		return // claimed, changed since ReadyTasks, or still launching
+	}
+	task, _ := g.Snapshot(id)
+
 	a, ok := d.agents[task.Role]
 	if !ok {
 		log.Printf("[dispatch] skipping task %s: no agent for role %s", task.ID, task.Role)
 		_ = g.SetStatus(task.ID, model.StatusFailed)
 		_ = g.SetError(task.ID, fmt.Sprintf("no agent for role %s", task.Role))
-		return nil
+		return
 	}
+	_ = g.StartAttempt(id, time.Now())
 
-	paneID, err := a.Launch(d.session, task)
-	if err != nil {
-		log.Printf("[dispatch] failed to launch task %s: %v", task.ID, err)
-		_ = g.SetStatus(task.ID, model.StatusFailed)
-		_ = g.SetError(task.ID, err.Error())
-		return nil
-	}
+	d.mu.Lock()
+	d.inflight[id] = true
+	d.mu.Unlock()
 
-	_ = g.SetStatus(task.ID, model.StatusRunning)
-	_ = g.SetPaneID(task.ID, paneID)
-	task.StartedAt = time.Now().Unix()
+	go func() {
+		defer func() {
+			d.mu.Lock()
+			delete(d.inflight, id)
+			d.mu.Unlock()
+			if d.onLaunched != nil {
+				d.onLaunched()
+			}
+		}()
 
-	log.Printf("[dispatch] -> %s (%s) in pane %s", task.ID, task.Role, paneID)
-	return nil
+		if d.onStart != nil {
+			d.onStart(id)
+		}
+		task, _ := g.Snapshot(id)
+		paneID, err := a.Launch(d.session, &task)
+		if err != nil {
+			log.Printf("[dispatch] failed to launch task %s: %v", task.ID, err)
+			if d.onLaunchFailed != nil {
+				d.onLaunchFailed(task.ID, err)
+				return
+			}
+			if g.Transition(task.ID, model.StatusLaunching, model.StatusFailed) {
+				_ = g.SetError(task.ID, fmt.Sprintf("launch failed: %v", err))
+			}
+			return
+		}
+
+		if !g.MarkRunning(task.ID, paneID, time.Now()) {
+			// Cancelled or retried while launching: the new pane is orphaned.
+			log.Printf("[dispatch] %s changed while launching; closing pane %s", task.ID, paneID)
+			_ = tmux.KillPane(paneID)
+			return
+		}
+		_ = g.SetAttemptLaunch(task.ID, paneID, tmux.PromptPath(task.ID, len(task.History)))
+
+		log.Printf("[dispatch] -> %s (%s) in pane %s", task.ID, task.Role, paneID)
+	}()
 }
@@ -0,0 +1,163 @@
+package orchestrator
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hubenschmidt/claude-dag/internal/model"
+)
+
+// statusColors fills graph nodes by task status.
+var statusColors = map[model.TaskStatus]string{
+	model.StatusPending:   "#e0e0e0",
+	model.StatusLaunching: "#bbdefb",
+	model.StatusRunning:   "#90caf9",
+	model.StatusCompleted: "#a5d6a7",
+	model.StatusFailed:    "#ef9a9a",
+	model.StatusRejected:  "#ffcc80",
+	model.StatusCancelled: "#b0bec5",
+	model.StatusFixing:    "#ffe082",
+	model.StatusBlocked:   "#ce93d8",
+	model.StatusSkipped:   "#bdbdbd",
+
+	model.StatusAwaitingApproval: "#fff59d",
+}
+
+const unknownStatusColor = "#ffffff"
+
+func statusColor(s model.TaskStatus) string {
+	if c, ok := statusColors[s]; ok {
+		return c
+	}
+	return unknownStatusColor
+}
+
+// nodeLabel is the text shown in a task's node: ID, role and status, plus the
+// attempt number once a task has been relaunched for any reason.
+func nodeLabel(t *model.Task, newline string) string {
+	label := t.ID + newline + fmt.Sprintf("%s · %s", t.Role, t.Status)
+	if n := len(t.History); n > 1 {
+		label += newline + fmt.Sprintf("attempt %d", n)
+	}
+	return label
+}
+
+// checkEdge returns the task t checks or repairs, and how: a reviewer
+// reviews it, a tester tests it, a fixer fixes it. to is empty for any other
+// task.
+func checkEdge(t *model.Task) (to, label string) {
+	switch t.Role {
+	case model.RoleReviewer:
+		return t.ReviewTaskID, "reviews"
+	case model.RoleTester:
+		return t.TestTaskID, "tests"
+	case model.RoleFixer:
+		return t.FixTaskID, "fixes"
+	}
+	return "", ""
+}
+
+// dotEdgeStyles tells the kinds of check edge apart in WriteDOT.
+var dotEdgeStyles = map[string]string{
+	"reviews": `style=dashed, color="#757575"`,
+	"tests":   `style=dotted, color="#2e7d32", penwidth=2`,
+	"fixes":   `style=bold, color="#ef6c00"`,
+}
+
+// mermaidArrows and mermaidEdgeColors tell the kinds of check edge apart in
+// WriteMermaid.
+var (
+	mermaidArrows = map[string]string{
+		"reviews": "-. reviews .->",
+		"tests":   "-. tests .->",
+		"fixes":   "== fixes ==>",
+	}
+	mermaidEdgeColors = map[string]string{
+		"reviews": "#757575",
+		"tests":   "#2e7d32",
+		"fixes":   "#ef6c00",
+	}
+)
+
+// WriteDOT renders g as a Graphviz digraph. Solid edges are DependsOn edges;
+// labelled edges point from a reviewer, tester or fixer to the task it
+// reviews (dashed grey), tests (dotted green) or fixes (bold orange).
+func WriteDOT(w io.Writer, g *Graph) {
+	tasks := g.Tasks()
+
+	fmt.Fprintln(w, "digraph dag {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	fmt.Fprintln(w, `  node [shape=box, style="rounded,filled", fontname="Helvetica"];`)
+	for _, t := range tasks {
+		fmt.Fprintf(w, "  %s [label=%s, fillcolor=%q];\n", dotQuote(t.ID), dotQuote(nodeLabel(t, "\n")), statusColor(t.Status))
+	}
+	for _, t := range tasks {
+		for _, dep := range t.DependsOn {
+			fmt.Fprintf(w, "  %s -> %s;\n", dotQuote(dep), dotQuote(t.ID))
+		}
+		if to, label := checkEdge(t); to != "" {
+			fmt.Fprintf(w, "  %s -> %s [%s, label=%q, constraint=false];\n", dotQuote(t.ID), dotQuote(to), dotEdgeStyles[label], label)
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// WriteMermaid renders g as a Mermaid flowchart with the same edge
+// conventions as WriteDOT: dotted edges from reviewers and testers and thick
+// ones from fixers, coloured alike.
+func WriteMermaid(w io.Writer, g *Graph) {
+	tasks := g.Tasks()
+
+	// Task IDs may contain characters Mermaid rejects in node IDs.
+	ids := make(map[string]string, len(tasks))
+	for i, t := range tasks {
+		ids[t.ID] = fmt.Sprintf("t%d", i)
+	}
+
+	fmt.Fprintln(w, "flowchart LR")
+	for _, t := range tasks {
+		fmt.Fprintf(w, "  %s[\"%s\"]:::%s\n", ids[t.ID], mermaidEscape(nodeLabel(t, "<br/>")), mermaidClass(t.Status))
+	}
+	edges := 0 // linkStyle addresses edges by the order they are declared in
+	var linkStyles []string
+	for _, t := range tasks {
+		for _, dep := range t.DependsOn {
+			if from, ok := ids[dep]; ok {
+				fmt.Fprintf(w, "  %s --> %s\n", from, ids[t.ID])
+				edges++
+			}
+		}
+		if to, label := checkEdge(t); ids[to] != "" {
+			fmt.Fprintf(w, "  %s %s %s\n", ids[t.ID], mermaidArrows[label], ids[to])
+			linkStyles = append(linkStyles, fmt.Sprintf("  linkStyle %d stroke:%s", edges, mermaidEdgeColors[label]))
+			edges++
+		}
+	}
+	for _, s := range linkStyles {
+		fmt.Fprintln(w, s)
+	}
+
+	seen := map[string]bool{}
+	for _, t := range tasks {
+		class := mermaidClass(t.Status)
+		if seen[class] {
+			continue
+		}
+		seen[class] = true
+		fmt.Fprintf(w, "  classDef %s fill:%s,stroke:#616161\n", class, statusColor(t.Status))
+	}
+}
+
+// mermaidClass turns a status into a valid Mermaid class name.
+func mermaidClass(s model.TaskStatus) string {
+	return "st_" + strings.NewReplacer("-", "_", " ", "_").Replace(string(s))
+}
+
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
@@ -0,0 +1,49 @@
+package orchestrator
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hubenschmidt/claude-dag/internal/model"
+)
+
+// propagateFailures blocks the dependents of failed tasks and releases those
+// whose failure was retried.
+func (o *Orchestrator) propagateFailures() {
+	blocked, unblocked := o.graph.UpdateBlocked()
+	for _, id := range blocked {
+		t, _ := o.graph.Get(id)
+		o.logEvent("blocked: %s (by %s)", id, t.BlockedBy)
+	}
+	for _, id := range unblocked {
+		o.logEvent("unblocked: %s", id)
+	}
+}
+
+// PrintBlocked lists each failed or cancelled task with the tasks it blocks or
+// caused to be skipped. It prints nothing if no task has failed.
+func PrintBlocked(w io.Writer, g *Graph) {
+	var failed []*model.Task
+	blocks := make(map[string][]string)
+	for _, t := range g.Tasks() {
+		switch {
+		case isFailure(t.Status):
+			failed = append(failed, t)
+		case t.Status == model.StatusBlocked || t.Status == model.StatusSkipped:
+			blocks[t.BlockedBy] = append(blocks[t.BlockedBy], t.ID)
+		}
+	}
+	if len(failed) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "--- Failures ---")
+	for _, t := range failed {
+		fmt.Fprintf(w, "  %s (%s): %s\n", t.ID, t.Status, t.Error)
+		if ids := blocks[t.ID]; len(ids) > 0 {
+			fmt.Fprintf(w, "    blocks: %s\n", strings.Join(ids, ", "))
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package orchestrator
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hubenschmidt/claude-dag/internal/artifact"
+	"github.com/hubenschmidt/claude-dag/internal/model"
+	"github.com/hubenschmidt/claude-dag/internal/tmux"
+)
+
+// fileRef matches anything in command output that looks like a file path,
+// e.g. "./handlers.go" in "./handlers.go:12:3: undefined: store".
+var fileRef = regexp.MustCompile(`[\w./-]+\.\w+`)
+
+// startFixer hands a task that failed verification to a fixer: a narrow
+// agent given only the failing output and the files it points at. The task
+// waits in StatusFixing until the fixer gets the build green or gives up. It
+// reports false, leaving the task running, if no fixer could be started,
+// including when there are no files to hand it: the builder then reworks.
+func (o *Orchestrator) startFixer(id, detail string) bool {
+	t, ok := o.graph.Snapshot(id)
+	if !ok {
+		return false
+	}
+	var changed []string
+	if n := len(t.History); n > 0 {
+		changed = changedFiles(t.OutputDir, time.Unix(t.History[n-1].StartedAt, 0))
+	}
+	files := affectedFiles(t.OutputDir, detail, changed)
+	if len(files) == 0 || !o.graph.Transition(id, model.StatusRunning, model.StatusFixing) {
+		return false
+	}
+
+	fixID := "fix-" + id
+	if old, exists := o.graph.Get(fixID); exists {
+		// Finished fixing an earlier attempt; its pane may still be open.
+		if old.PaneID != "" {
+			_ = tmux.KillPane(old.PaneID)
+		}
+		_ = o.graph.RemoveTask(fixID)
+	}
+	fixer := &model.Task{
+		ID:          fixID,
+		Role:        model.RoleFixer,
+		Description: fmt.Sprintf("Fix the verification failure of task %s", id),
+		OutputDir:   t.OutputDir,
+		FixTaskID:   id,
+		Files:       files,
+		Feedback:    detail,
+		Priority:    t.Priority + 1, // ahead of new work; the task's dependents wait on it
+	}
+	if err := o.addTask(fixer); err != nil {
+		o.logEvent("fixer for %s: %v", id, err)
+		o.graph.Transition(id, model.StatusFixing, model.StatusRunning)
+		return false
+	}
+	_ = o.graph.SetFixTaskID(id, fixID)
+	o.logEvent("task %s failed verification (%s), fixer %s queued for %d file(s)", id, summarize(detail), fixID, len(fixer.Files))
+	return true
+}
+
+// processFixes settles tasks whose fixer has finished: fixed tasks complete,
+// and a task whose fixer gave up, or edited files it wasn't given, fails
+// verification, re-queuing its original builder with the last failing output
+// as feedback.
+func (o *Orchestrator) processFixes() {
+	for _, f := range o.graph.Tasks() {
+		if f.Role != model.RoleFixer {
+			continue
+		}
+		t, ok := o.graph.Get(f.FixTaskID)
+		if !ok || t.Status != model.StatusFixing || o.filesPending(f.ID) {
+			continue // or wait for the files the fixer changed
+		}
+
+		stray := strayEdits(f)
+		switch {
+		case f.Status == model.StatusCompleted && len(stray) == 0:
+			o.complete(t.ID, model.StatusFixing, "fixed by "+f.ID)
+		case f.Status == model.StatusCompleted:
+			_ = o.graph.RemoveTask(f.ID)
+			_ = o.graph.SetFixTaskID(t.ID, "")
+			o.logEvent("fixer %s edited files outside its list (%s), re-running %s", f.ID, strings.Join(stray, ", "), t.ID)
+			o.failTask(t.ID, model.StatusFixing, model.FailVerification, fmt.Sprintf("Fixer %s edited files it was not given: %s\n\n%s", f.ID, strings.Join(stray, ", "), f.Feedback))
+		case f.Status == model.StatusFailed:
+			detail := cmp.Or(f.Feedback, f.Error)
+			_ = o.graph.RemoveTask(f.ID)
+			_ = o.graph.SetFixTaskID(t.ID, "")
+			o.logEvent("fixer %s gave up (%s), re-running %s", f.ID, f.Error, t.ID)
+			o.failTask(t.ID, model.StatusFixing, model.FailVerification, detail)
+		}
+	}
+}
+
+// strayEdits returns the files the fixer f's last attempt changed that it
+// wasn't given. An attempt that overlapped another task's in the same output
+// dir can't tell its edits apart from that task's, so it is not checked.
+func strayEdits(f *model.Task) []string {
+	n := len(f.History)
+	if n == 0 || len(f.History[n-1].Overlapped) > 0 {
+		return nil
+	}
+	var stray []string
+	for _, p := range f.History[n-1].FilesChanged {
+		if !slices.Contains(f.Files, p) {
+			stray = append(stray, p)
+		}
+	}
+	return stray
+}
+
+// verifyCommand returns the command that verifies t's output. A fixer is
+// verified by the command of the task it fixes.
+func (o *Orchestrator) verifyCommand(t *model.Task) string {
+	if t.Role != model.RoleFixer {
+		return o.config.Role(t.Role).Verify
+	}
+	fixed, ok := o.graph.Get(t.FixTaskID)
+	if !ok {
+		return ""
+	}
+	return o.config.Role(fixed.Role).Verify
+}
+
+// affectedFiles lists the files under outputDir that the failing output
+// refers to, relative to it. If it names none, the files the failed attempt
+// changed are used instead.
+func affectedFiles(outputDir, output string, changed []string) []string {
+	root := filepath.Join(artifact.BaseDir, outputDir)
+	seen := map[string]bool{}
+	var files []string
+	for _, ref := range fileRef.FindAllString(output, -1) {
+		rel := strings.TrimPrefix(strings.TrimPrefix(ref, root+"/"), "./")
+		if seen[rel] || filepath.IsAbs(rel) || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		seen[rel] = true
+		if info, err := os.Stat(filepath.Join(root, rel)); err == nil && !info.IsDir() {
+			files = append(files, rel)
+		}
+	}
+	if len(files) == 0 {
+		return changed
+	}
+	return files
+}
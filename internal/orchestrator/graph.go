@@ -1,8 +1,15 @@
 package orchestrator
 
 import (
+	"encoding/json"
 	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
 	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/hubenschmidt/claude-dag/internal/model"
 )
@@ -11,6 +18,8 @@ type Graph struct {
 	mu    sync.RWMutex
 	tasks map[string]*model.Task
 	order []string // insertion order
+
+	planExpanded bool // every entry of the task plan has been added
 }
 
 func NewGraph() *Graph {
@@ -39,32 +48,143 @@ func (g *Graph) AddTask(t *model.Task) error {
 	return nil
 }
 
+// RemoveTask drops a task no other task depends on, such as a fixer that
+// gave up.
+func (g *Graph) RemoveTask(id string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.tasks[id]; !ok {
+		return fmt.Errorf("task %s not found", id)
+	}
+	for _, t := range g.tasks {
+		if slices.Contains(t.DependsOn, id) {
+			return fmt.Errorf("task %s is a dependency of %s", id, t.ID)
+		}
+	}
+	delete(g.tasks, id)
+	g.order = slices.DeleteFunc(g.order, func(o string) bool { return o == id })
+	return nil
+}
+
 func (g *Graph) ReadyTasks() []*model.Task {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
 
+	now := time.Now().Unix()
 	var ready []*model.Task
 	for _, id := range g.order {
 		t := g.tasks[id]
-		if t.Status != model.StatusPending {
+		if t.Status != model.StatusPending || t.RetryAfter > now {
 			continue
 		}
 		if g.depsResolved(t) {
-			ready = append(ready, t)
+			ready = append(ready, clone(t))
 		}
 	}
 	return ready
 }
 
+// Downstream returns the IDs of every task that depends on id, directly or
+// transitively.
+func (g *Graph) Downstream(id string) map[string]bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	down := map[string]bool{}
+	frontier := []string{id}
+	for len(frontier) > 0 {
+		cur := frontier[0]
+		frontier = frontier[1:]
+		for _, tid := range g.order {
+			if !down[tid] && slices.Contains(g.tasks[tid].DependsOn, cur) {
+				down[tid] = true
+				frontier = append(frontier, tid)
+			}
+		}
+	}
+	return down
+}
+
+// Splice records children as tasks split off from parent and makes every
+// direct dependent of parent also wait on them, bar the tasks in keep, which
+// only need parent itself.
+func (g *Graph) Splice(parent string, children []string, keep map[string]bool) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	p, ok := g.tasks[parent]
+	if !ok {
+		return fmt.Errorf("task %s not found", parent)
+	}
+	for _, id := range children {
+		if _, ok := g.tasks[id]; !ok {
+			return fmt.Errorf("task %s not found", id)
+		}
+	}
+	isChild := map[string]bool{}
+	for _, id := range children {
+		isChild[id] = true
+	}
+	for _, t := range g.tasks {
+		if isChild[t.ID] || keep[t.ID] || !slices.Contains(t.DependsOn, parent) {
+			continue
+		}
+		for _, c := range children {
+			if !slices.Contains(t.DependsOn, c) {
+				t.DependsOn = append(t.DependsOn, c)
+			}
+		}
+	}
+	p.Children = append(p.Children, children...)
+	return nil
+}
+
+// depsResolved reports whether every dependency of t is completed and
+// accepted. A task's own reviewers and tester only need it completed: they
+// are what accepts it.
 func (g *Graph) depsResolved(t *model.Task) bool {
-	for _, dep := range t.DependsOn {
-		if g.tasks[dep].Status != model.StatusCompleted {
+	for _, id := range t.DependsOn {
+		dep := g.tasks[id]
+		checks := t.ReviewTaskID == id || (t.Role == model.RoleTester && t.TestTaskID == id)
+		if dep.Status != model.StatusCompleted || (!checks && !g.accepted(dep)) {
 			return false
 		}
 	}
 	return true
 }
 
+// Accepted reports whether task id is completed and its output has passed
+// every gate: approval by its reviewers, its tests, and human sign-off if
+// it needs it.
+func (g *Graph) Accepted(id string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	t, ok := g.tasks[id]
+	return ok && g.accepted(t)
+}
+
+// accepted is Accepted for a task already looked up. The caller must hold
+// the lock.
+func (g *Graph) accepted(t *model.Task) bool {
+	if t.Status != model.StatusCompleted {
+		return false
+	}
+	if len(t.Reviewers()) > 0 {
+		n := len(t.History)
+		if n == 0 || !strings.HasPrefix(t.History[n-1].Verdict, "approved by") {
+			return false
+		}
+	}
+	if t.Role != model.RoleTester && t.TestTaskID != "" {
+		if tt, ok := g.tasks[t.TestTaskID]; !ok || tt.Result != testPassed {
+			return false
+		}
+	}
+	return !t.RequiresApproval || t.Approved
+}
+
 func (g *Graph) SetStatus(id string, status model.TaskStatus) error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
@@ -77,6 +197,21 @@ func (g *Graph) SetStatus(id string, status model.TaskStatus) error {
 	return nil
 }
 
+// Transition moves a task from one status to another only if it is still in
+// the from status, reporting whether it did. It lets the poll loop settle a
+// task without overwriting a concurrent control-socket change.
+func (g *Graph) Transition(id string, from, to model.TaskStatus) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	t, ok := g.tasks[id]
+	if !ok || t.Status != from {
+		return false
+	}
+	t.Status = to
+	return true
+}
+
 func (g *Graph) SetResult(id, result string) error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
@@ -101,11 +236,15 @@ func (g *Graph) SetError(id, errMsg string) error {
 	return nil
 }
 
+// Get returns a copy of a task, safe to read while the graph changes.
 func (g *Graph) Get(id string) (*model.Task, bool) {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
 	t, ok := g.tasks[id]
-	return t, ok
+	if !ok {
+		return nil, false
+	}
+	return clone(t), true
 }
 
 func (g *Graph) AllCompleted() bool {
@@ -120,31 +259,163 @@ func (g *Graph) AllCompleted() bool {
 	return true
 }
 
+// HasFailed reports whether any task failed or was cancelled.
 func (g *Graph) HasFailed() bool {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
 
 	for _, t := range g.tasks {
-		if t.Status == model.StatusFailed {
+		if isFailure(t.Status) {
 			return true
 		}
 	}
 	return false
 }
 
+// Tasks returns copies of every task in insertion order, safe to read while
+// the graph changes.
 func (g *Graph) Tasks() []*model.Task {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
 
 	tasks := make([]*model.Task, 0, len(g.order))
 	for _, id := range g.order {
-		tasks = append(tasks, g.tasks[id])
+		tasks = append(tasks, clone(g.tasks[id]))
 	}
 	return tasks
 }
 
-// RejectTask marks a task as rejected with feedback. If under max attempts,
-// resets to pending so it re-enters the dispatch queue.
+// RunningPane returns the pane of a running task, or "" if it isn't running.
+// Cancel marks a pending, launching or running task cancelled, returning
+// the pane of a running one. It fails for a task in any other state, which
+// has already finished or is waiting on someone else.
+func (g *Graph) Cancel(id string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	t, ok := g.tasks[id]
+	if !ok {
+		return "", fmt.Errorf("task %s not found", id)
+	}
+	switch t.Status {
+	case model.StatusPending, model.StatusLaunching, model.StatusRunning:
+	default:
+		return "", fmt.Errorf("task %s is %s, not pending or active", id, t.Status)
+	}
+	paneID := ""
+	if t.Status == model.StatusRunning {
+		paneID = t.PaneID
+	}
+	t.Status = model.StatusCancelled
+	return paneID, nil
+}
+
+func (g *Graph) RunningPane(id string) (string, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	t, ok := g.tasks[id]
+	if !ok {
+		return "", fmt.Errorf("task %s not found", id)
+	}
+	if t.Status != model.StatusRunning {
+		return "", nil
+	}
+	return t.PaneID, nil
+}
+
+// UpdateBlocked marks pending tasks that depend, directly or transitively, on
+// a failed or cancelled task as blocked by it, and returns blocked or skipped
+// tasks whose failure has since been retried to pending. It reports the IDs
+// it changed.
+func (g *Graph) UpdateBlocked() (blocked, unblocked []string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// One pass in topological order sees every dependency's final state.
+	// Insertion order won't do: spliced sub-plan tasks are added after the
+	// tasks that come to depend on them.
+	for _, id := range g.topoOrder() {
+		t := g.tasks[id]
+		if t.Status != model.StatusPending && t.Status != model.StatusBlocked && t.Status != model.StatusSkipped {
+			continue
+		}
+		cause := g.failedDependency(t)
+		switch {
+		case cause != "" && t.Status == model.StatusPending:
+			t.Status = model.StatusBlocked
+			t.BlockedBy = cause
+			blocked = append(blocked, id)
+		case cause != "":
+			t.BlockedBy = cause
+		case t.Status != model.StatusPending:
+			t.Status = model.StatusPending
+			t.BlockedBy = ""
+			unblocked = append(unblocked, id)
+		}
+	}
+	return blocked, unblocked
+}
+
+// topoOrder returns every task ID with dependencies before dependents, ties
+// in insertion order. The caller must hold the lock.
+func (g *Graph) topoOrder() []string {
+	order := make([]string, 0, len(g.order))
+	done := make(map[string]bool, len(g.order))
+	var visit func(id string)
+	visit = func(id string) {
+		if done[id] {
+			return
+		}
+		done[id] = true // set first so a cycle can't recurse forever
+		for _, dep := range g.tasks[id].DependsOn {
+			visit(dep)
+		}
+		order = append(order, id)
+	}
+	for _, id := range g.order {
+		visit(id)
+	}
+	return order
+}
+
+// failedDependency returns the failed task that keeps t from running, or ""
+// if none does.
+func (g *Graph) failedDependency(t *model.Task) string {
+	for _, dep := range t.DependsOn {
+		d := g.tasks[dep]
+		switch d.Status {
+		case model.StatusFailed, model.StatusCancelled:
+			return d.ID
+		case model.StatusBlocked, model.StatusSkipped:
+			return d.BlockedBy
+		}
+	}
+	return ""
+}
+
+// isFailure reports whether a task stopped without completing and won't run
+// again unless retried.
+func isFailure(s model.TaskStatus) bool {
+	return s == model.StatusFailed || s == model.StatusCancelled
+}
+
+// SkipBlocked marks every blocked task as skipped, for when the run gives up
+// on the failures blocking them.
+func (g *Graph) SkipBlocked() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, t := range g.tasks {
+		if t.Status == model.StatusBlocked {
+			t.Status = model.StatusSkipped
+		}
+	}
+}
+
+// RejectTask records a review rejection with feedback, whatever the task's
+// status. Within the task's retry budget it resets to pending so it re-enters
+// the dispatch queue.
 func (g *Graph) RejectTask(id, feedback string) error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
@@ -153,20 +424,209 @@ func (g *Graph) RejectTask(id, feedback string) error {
 	if !ok {
 		return fmt.Errorf("task %s not found", id)
 	}
+	fail(t, model.FailReviewRejection, feedback, time.Now())
+	return nil
+}
 
-	t.Attempts++
-	t.Feedback = feedback
+// Fail records a failed attempt of class on a task that is still in status
+// from, reporting whether it was. Like RejectTask, it re-queues the task if
+// its retry policy allows, otherwise fails it for good.
+func (g *Graph) Fail(id string, from model.TaskStatus, class model.FailureClass, detail string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	t, ok := g.tasks[id]
+	if !ok || t.Status != from {
+		return false
+	}
+	fail(t, class, detail, time.Now())
+	return true
+}
+
+// FailForGood is Fail for a failure no retry can fix: the task fails
+// whatever its retry policy allows.
+func (g *Graph) FailForGood(id string, from model.TaskStatus, class model.FailureClass, detail string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 
-	if t.Attempts >= model.MaxAttempts {
+	t, ok := g.tasks[id]
+	if !ok || t.Status != from {
+		return false
+	}
+	fail(t, class, detail, time.Now())
+	t.Status = model.StatusFailed
+	t.Error = fmt.Sprintf("%s, not retried: %s", class, summarize(detail))
+	t.RetryAfter = 0
+	return true
+}
+
+// fail counts a failure of class against t's budget for that class. Review
+// rejections and verification failures become the feedback for the next
+// attempt; transient failures delay it by the policy's backoff.
+func fail(t *model.Task, class model.FailureClass, detail string, now time.Time) {
+	var n int
+	if class == model.FailReviewRejection {
+		t.Attempts++
+		n = t.Attempts
+	} else {
+		if t.Failures == nil {
+			t.Failures = make(map[model.FailureClass]int)
+		}
+		t.Failures[class]++
+		n = t.Failures[class]
+	}
+	t.LastFailure = class
+	t.Approved = false
+	if class == model.FailReviewRejection || class == model.FailVerification {
+		t.Feedback = detail
+	}
+
+	policy := t.RetryPolicy()
+	switch {
+	case !policy.Retries(class):
+		t.Status = model.StatusFailed
+		t.Error = fmt.Sprintf("%s, not retried: %s", class, summarize(detail))
+	case n >= policy.MaxAttempts:
 		t.Status = model.StatusFailed
-		t.Error = fmt.Sprintf("rejected %d times, giving up", t.Attempts)
-		return nil
+		t.Error = fmt.Sprintf("%s %d times, giving up: %s", class, n, summarize(detail))
+	default:
+		t.Status = model.StatusPending
+		t.Error = ""
+		t.RetryAfter = now.Add(policy.Delay(class, n)).Unix()
+	}
+}
+
+// summarize returns the first line of s, shortened for an error message
+// without splitting a rune.
+func summarize(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	if len(line) > 120 {
+		cut := 117
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		line = line[:cut] + "..."
+	}
+	return line
+}
+
+// NextRetry returns when the earliest pending task held back by a retry
+// backoff becomes ready, or the zero time if none is.
+func (g *Graph) NextRetry() time.Time {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	now := time.Now().Unix()
+	var next int64
+	for _, t := range g.tasks {
+		if t.Status != model.StatusPending || t.RetryAfter <= now {
+			continue
+		}
+		if next == 0 || t.RetryAfter < next {
+			next = t.RetryAfter
+		}
+	}
+	if next == 0 {
+		return time.Time{}
+	}
+	return time.Unix(next, 0)
+}
+
+// Prune removes every task not listed in keep, preserving the order of the
+// survivors. It is used to discard an expanded plan before re-expanding it,
+// so the plan no longer counts as expanded.
+func (g *Graph) Prune(keep ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.planExpanded = false
+
+	kept := make(map[string]bool, len(keep))
+	for _, id := range keep {
+		kept[id] = true
+	}
+
+	order := g.order[:0]
+	for _, id := range g.order {
+		if !kept[id] {
+			delete(g.tasks, id)
+			continue
+		}
+		order = append(order, id)
 	}
+	g.order = order
+}
+
+// PlanExpanded reports whether the task plan has been fully expanded.
+func (g *Graph) PlanExpanded() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.planExpanded
+}
 
+// SetPlanExpanded records that every entry of the task plan has been added.
+func (g *Graph) SetPlanExpanded() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.planExpanded = true
+}
+
+// Retry resets a task to pending with a fresh budget for every failure
+// class, replacing its feedback and clearing any error.
+func (g *Graph) Retry(id, feedback string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	t, ok := g.tasks[id]
+	if !ok {
+		return fmt.Errorf("task %s not found", id)
+	}
+	t.Attempts = 0
+	t.Failures = nil
+	t.LastFailure = ""
+	t.RetryAfter = 0
 	t.Status = model.StatusPending
+	t.Feedback = feedback
+	t.Error = ""
+	t.Approved = false
 	return nil
 }
 
+// Approve records human sign-off on a task awaiting approval and completes it.
+func (g *Graph) Approve(id string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	t, ok := g.tasks[id]
+	if !ok {
+		return fmt.Errorf("task %s not found", id)
+	}
+	if t.Status != model.StatusAwaitingApproval {
+		return fmt.Errorf("task %s is %s, not awaiting approval", id, t.Status)
+	}
+	t.Approved = true
+	t.Status = model.StatusCompleted
+	return nil
+}
+
+// MarkRunning moves a launching task to running in pane paneID, started at
+// the given time, all in one step so no reader sees it running with the
+// previous attempt's pane or start time. It reports false if the task is no
+// longer launching.
+func (g *Graph) MarkRunning(id, paneID string, at time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	t, ok := g.tasks[id]
+	if !ok || t.Status != model.StatusLaunching {
+		return false
+	}
+	t.Status = model.StatusRunning
+	t.PaneID = paneID
+	t.StartedAt = at.Unix()
+	return true
+}
+
 // SetPaneID stores the tmux pane ID on a task.
 func (g *Graph) SetPaneID(id, paneID string) error {
 	g.mu.Lock()
@@ -180,20 +640,178 @@ func (g *Graph) SetPaneID(id, paneID string) error {
 	return nil
 }
 
-// RunningCount returns the number of tasks currently in running status.
+// SetReviewTaskID links a code task to the reviewer task that reviews it.
+func (g *Graph) SetReviewTaskID(id, reviewID string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	t, ok := g.tasks[id]
+	if !ok {
+		return fmt.Errorf("task %s not found", id)
+	}
+	t.ReviewTaskID = reviewID
+	return nil
+}
+
+// SetTestTaskID links a code task to the tester task that tests it.
+func (g *Graph) SetTestTaskID(id, testID string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	t, ok := g.tasks[id]
+	if !ok {
+		return fmt.Errorf("task %s not found", id)
+	}
+	t.TestTaskID = testID
+	return nil
+}
+
+// SetFixTaskID links a task to the fixer repairing its output.
+func (g *Graph) SetFixTaskID(id, fixID string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	t, ok := g.tasks[id]
+	if !ok {
+		return fmt.Errorf("task %s not found", id)
+	}
+	t.FixTaskID = fixID
+	return nil
+}
+
+// SetCacheKey records the hash of a task's inputs.
+func (g *Graph) SetCacheKey(id, key string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	t, ok := g.tasks[id]
+	if !ok {
+		return fmt.Errorf("task %s not found", id)
+	}
+	t.CacheKey = key
+	return nil
+}
+
+// SetCachedAttempt records which attempt's output was stored in the cache.
+func (g *Graph) SetCachedAttempt(id string, n int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	t, ok := g.tasks[id]
+	if !ok {
+		return fmt.Errorf("task %s not found", id)
+	}
+	t.CachedAttempt = n
+	return nil
+}
+
+// SetChanged records the files a reviewer's task has written.
+func (g *Graph) SetChanged(id string, files []string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	t, ok := g.tasks[id]
+	if !ok {
+		return fmt.Errorf("task %s not found", id)
+	}
+	t.Changed = files
+	return nil
+}
+
+// SetPanel links a code task to the panel of reviewer tasks that review it.
+func (g *Graph) SetPanel(id string, reviewIDs []string, consensus model.Consensus) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	t, ok := g.tasks[id]
+	if !ok {
+		return fmt.Errorf("task %s not found", id)
+	}
+	t.Panel = reviewIDs
+	t.Consensus = consensus
+	return nil
+}
+
+// SetStartedAt records when a task's current attempt was launched.
+func (g *Graph) SetStartedAt(id string, unix int64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	t, ok := g.tasks[id]
+	if !ok {
+		return fmt.Errorf("task %s not found", id)
+	}
+	t.StartedAt = unix
+	return nil
+}
+
+// SetFinishedAt records when a task's current attempt finished.
+func (g *Graph) SetFinishedAt(id string, unix int64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	t, ok := g.tasks[id]
+	if !ok {
+		return fmt.Errorf("task %s not found", id)
+	}
+	t.FinishedAt = unix
+	return nil
+}
+
+// RunningCount returns the number of tasks currently launching or running,
+// i.e. occupying an agent slot.
 func (g *Graph) RunningCount() int {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
 
 	count := 0
 	for _, t := range g.tasks {
-		if t.Status == model.StatusRunning {
+		if t.Status == model.StatusRunning || t.Status == model.StatusLaunching {
 			count++
 		}
 	}
 	return count
 }
 
+// Snapshot returns a copy of a task taken under the read lock, safe to use
+// from another goroutine while the graph keeps changing.
+func (g *Graph) Snapshot(id string) (model.Task, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	t, ok := g.tasks[id]
+	if !ok {
+		return model.Task{}, false
+	}
+	return *clone(t), true
+}
+
+// clone deep-copies a task so callers can read it without holding the lock.
+// The caller must hold at least the read lock.
+func clone(t *model.Task) *model.Task {
+	c := *t
+	c.DependsOn = slices.Clone(t.DependsOn)
+	c.ArtifactDirs = slices.Clone(t.ArtifactDirs)
+	c.Failures = maps.Clone(t.Failures)
+	c.History = slices.Clone(t.History)
+	for i := range c.History {
+		c.History[i].FilesChanged = slices.Clone(t.History[i].FilesChanged)
+		c.History[i].Overlapped = slices.Clone(t.History[i].Overlapped)
+	}
+	c.Panel = slices.Clone(t.Panel)
+	c.Lenses = slices.Clone(t.Lenses)
+	c.Files = slices.Clone(t.Files)
+	c.Children = slices.Clone(t.Children)
+	c.Paths = slices.Clone(t.Paths)
+	c.Changed = slices.Clone(t.Changed)
+	if t.Retry != nil {
+		r := *t.Retry
+		r.RetryOn = slices.Clone(t.Retry.RetryOn)
+		c.Retry = &r
+	}
+	return &c
+}
+
 // SetFeedback stores review feedback on a task.
 func (g *Graph) SetFeedback(id, feedback string) error {
 	g.mu.Lock()
@@ -206,3 +824,149 @@ func (g *Graph) SetFeedback(id, feedback string) error {
 	t.Feedback = feedback
 	return nil
 }
+
+// StartAttempt opens a new entry in a task's attempt history.
+func (g *Graph) StartAttempt(id string, at time.Time) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	t, ok := g.tasks[id]
+	if !ok {
+		return fmt.Errorf("task %s not found", id)
+	}
+	t.History = append(t.History, model.Attempt{Number: len(t.History) + 1, StartedAt: at.Unix()})
+	return nil
+}
+
+// SetAttemptLaunch records the pane and prompt file of a task's current attempt.
+func (g *Graph) SetAttemptLaunch(id, paneID, promptFile string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	a, err := g.lastAttempt(id)
+	if err != nil {
+		return err
+	}
+	a.PaneID = paneID
+	a.PromptFile = promptFile
+	return nil
+}
+
+// EndAttempt closes a task's current attempt, unless it is already closed.
+func (g *Graph) EndAttempt(id, completion, errMsg string, at time.Time, files []string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	a, err := g.lastAttempt(id)
+	if err != nil || a.FinishedAt != 0 {
+		return err
+	}
+	a.FinishedAt = at.Unix()
+	a.Completion = completion
+	a.Error = errMsg
+	a.FilesChanged = files
+	return nil
+}
+
+// SetAttemptFiles records the files attempt n of a task changed, where its
+// manifest was saved, and the tasks whose attempts overlapped it in the same
+// output dir.
+func (g *Graph) SetAttemptFiles(id string, n int, files []string, manifest string, overlapped []string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	t, ok := g.tasks[id]
+	if !ok {
+		return fmt.Errorf("task %s not found", id)
+	}
+	if n < 1 || n > len(t.History) {
+		return fmt.Errorf("task %s has no attempt %d", id, n)
+	}
+	a := &t.History[n-1]
+	a.FilesChanged = files
+	a.Manifest = manifest
+	a.Overlapped = overlapped
+	return nil
+}
+
+// SetAttemptVerdict records the review outcome of a task's current attempt.
+func (g *Graph) SetAttemptVerdict(id, verdict, feedback string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	a, err := g.lastAttempt(id)
+	if err != nil {
+		return err
+	}
+	a.Verdict = verdict
+	a.Feedback = feedback
+	return nil
+}
+
+// lastAttempt returns a task's current attempt. The caller holds g.mu.
+func (g *Graph) lastAttempt(id string) (*model.Attempt, error) {
+	t, ok := g.tasks[id]
+	if !ok {
+		return nil, fmt.Errorf("task %s not found", id)
+	}
+	if len(t.History) == 0 {
+		return nil, fmt.Errorf("task %s has no attempts", id)
+	}
+	return &t.History[len(t.History)-1], nil
+}
+
+// graphState is the on-disk form of a Graph: tasks in insertion order, and
+// how far the plan has got. Checkpoints saved before PlanExpanded was
+// recorded leave it out.
+type graphState struct {
+	PlanExpanded *bool         `json:"plan_expanded,omitempty"`
+	Tasks        []*model.Task `json:"tasks"`
+}
+
+// Save writes the graph to path as JSON. The file is replaced atomically so
+// readers never see a partial checkpoint.
+func (g *Graph) Save(path string) error {
+	g.mu.RLock()
+	state := graphState{PlanExpanded: &g.planExpanded, Tasks: make([]*model.Task, 0, len(g.order))}
+	for _, id := range g.order {
+		state.Tasks = append(state.Tasks, g.tasks[id])
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	g.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("encode graph: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", tmp, err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadGraph reads a graph saved by Save, keeping every task's status.
+func LoadGraph(path string) (*Graph, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	var state graphState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	g := NewGraph()
+	for _, t := range state.Tasks {
+		if _, exists := g.tasks[t.ID]; exists {
+			return nil, fmt.Errorf("duplicate task %s in %s", t.ID, path)
+		}
+		g.tasks[t.ID] = t
+		g.order = append(g.order, t.ID)
+	}
+	if state.PlanExpanded != nil {
+		g.planExpanded = *state.PlanExpanded
+	} else {
+		g.planExpanded = len(g.order) > 1 // the design task alone was all there was before expanding
+	}
+	return g, nil
+}
@@ -0,0 +1,225 @@
+package orchestrator
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hubenschmidt/claude-dag/internal/agent"
+	"github.com/hubenschmidt/claude-dag/internal/artifact"
+	"github.com/hubenschmidt/claude-dag/internal/model"
+)
+
+// endAttempt closes a task's current attempt, then lists the files written
+// to its output directory since the attempt started in the background (see
+// recordFiles).
+func (o *Orchestrator) endAttempt(id, completion, errMsg string) {
+	t, ok := o.graph.Snapshot(id)
+	if !ok || len(t.History) == 0 || t.History[len(t.History)-1].FinishedAt != 0 {
+		return
+	}
+	now := time.Now()
+	overlapped := o.overlapping(&t, now)
+	_ = o.graph.EndAttempt(id, completion, errMsg, now, nil)
+	o.recordFiles(t, overlapped)
+}
+
+// recordFiles lists the files t's just-ended attempt wrote, from its
+// manifest, or by modification time if it has none, and records them with
+// the tasks that overlapped it. Tasks sharing an output directory may see
+// each other's files. It runs in the background, as snapshotting a large
+// output dir, such as a brownfield repo, takes a while; until it is done,
+// filesPending reports true for t, and a later attempt of t waits for it in
+// startAttempt.
+func (o *Orchestrator) recordFiles(t model.Task, overlapped []string) {
+	done := make(chan struct{})
+	o.filesMu.Lock()
+	prev := o.listingFiles[t.ID]
+	o.listingFiles[t.ID] = done
+	o.filesMu.Unlock()
+
+	o.filesWG.Add(1)
+	go func() {
+		defer func() {
+			o.filesMu.Lock()
+			if o.listingFiles[t.ID] == done {
+				delete(o.listingFiles, t.ID)
+			}
+			o.filesMu.Unlock()
+			close(done)
+			o.filesWG.Done()
+			o.notify()
+		}()
+		if prev != nil {
+			<-prev // the previous attempt's listing, so they land in order
+		}
+
+		a := t.History[len(t.History)-1]
+		files, path, ok := o.endManifest(&t)
+		if !ok {
+			files = changedFiles(t.OutputDir, time.Unix(a.StartedAt, 0))
+		}
+		_ = o.graph.SetAttemptFiles(t.ID, a.Number, files, path, overlapped)
+	}()
+}
+
+// filesPending reports whether the files of a task's last ended attempt are
+// still being listed.
+func (o *Orchestrator) filesPending(id string) bool {
+	o.filesMu.Lock()
+	defer o.filesMu.Unlock()
+	return o.listingFiles[id] != nil
+}
+
+// awaitFiles blocks until the files of a task's last ended attempt are
+// listed.
+func (o *Orchestrator) awaitFiles(id string) {
+	o.filesMu.Lock()
+	done := o.listingFiles[id]
+	o.filesMu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+// saveFeedbackLog writes every round of feedback on a task to
+// artifacts/feedback/<task>.md, where its reviewer reads it on re-review.
+func (o *Orchestrator) saveFeedbackLog(id string) {
+	t, ok := o.graph.Snapshot(id)
+	if !ok {
+		return
+	}
+	rounds := t.FeedbackRounds()
+	if len(rounds) == 0 {
+		return
+	}
+	if err := artifact.Write("feedback", id+".md", agent.FormatFeedbackRounds(rounds)); err != nil {
+		log.Printf("[orchestrator] feedback log for %s: %v", id, err)
+	}
+}
+
+// changedFiles lists files under an output directory modified at or after
+// since, relative to that directory. Sentinels are left out.
+func changedFiles(outputDir string, since time.Time) []string {
+	if outputDir == "" {
+		return nil
+	}
+	root := filepath.Join(artifact.BaseDir, outputDir)
+	var files []string
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasPrefix(d.Name(), ".done") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.ModTime().Before(since) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err == nil {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files
+}
+
+// PrintAttempts writes one line per attempt of t, for run summaries.
+func PrintAttempts(w io.Writer, t *model.Task) {
+	for _, a := range t.History {
+		line := fmt.Sprintf("    #%d %s %s", a.Number, time.Unix(a.StartedAt, 0).Format("15:04:05"), attemptDuration(a))
+		if a.Completion != "" {
+			line += " " + a.Completion
+		}
+		if len(a.FilesChanged) > 0 {
+			line += fmt.Sprintf(", %d file(s)", len(a.FilesChanged))
+		}
+		if a.Verdict != "" {
+			line += "; " + a.Verdict
+		}
+		if detail := cmp.Or(a.Feedback, a.Error); detail != "" {
+			line += ": " + summarize(detail)
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// PrintTask writes everything recorded about t, including the full history
+// of its attempts.
+func PrintTask(w io.Writer, t *model.Task) {
+	fmt.Fprintf(w, "Task:        %s\n", t.ID)
+	fmt.Fprintf(w, "Role:        %s\n", t.Role)
+	fmt.Fprintf(w, "Status:      %s\n", t.Status)
+	if len(t.DependsOn) > 0 {
+		fmt.Fprintf(w, "Depends on:  %s\n", strings.Join(t.DependsOn, ", "))
+	}
+	if len(t.Panel) > 0 {
+		fmt.Fprintf(w, "Review:      %s (%s)\n", strings.Join(t.Panel, ", "), t.Consensus)
+	} else if t.ReviewTaskID != "" {
+		fmt.Fprintf(w, "Review:      %s\n", t.ReviewTaskID)
+	}
+	if t.Lens != "" {
+		fmt.Fprintf(w, "Lens:        %s\n", t.Lens)
+	}
+	if len(t.Children) > 0 {
+		fmt.Fprintf(w, "Split into:  %s\n", strings.Join(t.Children, ", "))
+	}
+	if t.BlockedBy != "" {
+		fmt.Fprintf(w, "Blocked by:  %s\n", t.BlockedBy)
+	}
+	if t.Error != "" {
+		fmt.Fprintf(w, "Error:       %s\n", t.Error)
+	}
+	fmt.Fprintf(w, "Description: %s\n", t.Description)
+
+	if len(t.History) == 0 {
+		fmt.Fprintln(w, "\nnot launched yet")
+		return
+	}
+	for _, a := range t.History {
+		fmt.Fprintf(w, "\nAttempt %d\n", a.Number)
+		fmt.Fprintf(w, "  Started:   %s\n", time.Unix(a.StartedAt, 0).Format(time.DateTime))
+		if a.FinishedAt != 0 {
+			fmt.Fprintf(w, "  Finished:  %s (%s)\n", time.Unix(a.FinishedAt, 0).Format(time.DateTime), attemptDuration(a))
+		}
+		printField(w, "Pane", a.PaneID)
+		printField(w, "Prompt", a.PromptFile)
+		printField(w, "Manifest", a.Manifest)
+		printField(w, "Ended", a.Completion)
+		printField(w, "Error", a.Error)
+		printField(w, "Verdict", a.Verdict)
+		if a.Feedback != "" {
+			fmt.Fprintln(w, "  Feedback:")
+			for _, line := range strings.Split(strings.TrimRight(a.Feedback, "\n"), "\n") {
+				fmt.Fprintf(w, "    %s\n", line)
+			}
+		}
+		if len(a.FilesChanged) > 0 {
+			fmt.Fprintf(w, "  Files changed (%d):\n", len(a.FilesChanged))
+			for _, f := range a.FilesChanged {
+				fmt.Fprintf(w, "    %s\n", f)
+			}
+		}
+	}
+}
+
+func printField(w io.Writer, name, value string) {
+	if value != "" {
+		fmt.Fprintf(w, "  %-10s %s\n", name+":", value)
+	}
+}
+
+// attemptDuration is how long an attempt ran, or has run so far.
+func attemptDuration(a model.Attempt) string {
+	end := time.Now().Unix()
+	if a.FinishedAt != 0 {
+		end = a.FinishedAt
+	}
+	return (time.Duration(end-a.StartedAt) * time.Second).String()
+}
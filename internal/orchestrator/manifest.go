@@ -0,0 +1,151 @@
+package orchestrator
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/hubenschmidt/claude-dag/internal/artifact"
+	"github.com/hubenschmidt/claude-dag/internal/manifest"
+	"github.com/hubenschmidt/claude-dag/internal/model"
+)
+
+// SetManifestDir records a manifest of every task attempt in dir: the files
+// it added, modified and deleted in its output dir, found by snapshotting
+// the dir as the attempt starts and diffing it as the attempt ends.
+func (o *Orchestrator) SetManifestDir(dir string) {
+	o.manifestDir = dir
+}
+
+// manifestPath is where attempt n of a task's manifest is saved, or with
+// suffix ".before", the snapshot taken as it started.
+func (o *Orchestrator) manifestPath(id string, n int, suffix string) string {
+	return filepath.Join(o.manifestDir, fmt.Sprintf("%s.%d%s.json", id, n, suffix))
+}
+
+// startAttempt prepares a task's new attempt just before its agent launches:
+// it clears any sentinel an earlier attempt left, waits for the files of the
+// earlier attempt to be listed, snapshots the output dir and scopes a
+// reviewer to what it reviews. It runs in the launch goroutine, as hashing a
+// large dir takes a while.
+func (o *Orchestrator) startAttempt(id string) {
+	t, ok := o.graph.Snapshot(id)
+	if !ok || len(t.History) == 0 || t.History[len(t.History)-1].FinishedAt != 0 {
+		return // cancelled before the launch got this far
+	}
+	clearSentinel(t.OutputDir, id)
+	// The previous attempt's after-snapshot must not see this one's writes.
+	o.awaitFiles(id)
+	if t.Role == model.RoleReviewer && t.ReviewTaskID != "" {
+		o.awaitFiles(t.ReviewTaskID)
+		o.scopeReviewer(&t)
+	}
+	if o.manifestDir == "" || t.OutputDir == "" {
+		return
+	}
+	snap, err := manifest.Take(filepath.Join(artifact.BaseDir, t.OutputDir))
+	if err == nil {
+		err = manifest.Save(o.manifestPath(id, t.History[len(t.History)-1].Number, ".before"), snap)
+	}
+	if err != nil {
+		log.Printf("[manifest] %s: %v", id, err)
+	}
+}
+
+// endManifest diffs the output dir of t's current attempt against its start
+// snapshot and saves the manifest. It returns the files the attempt wrote
+// and the manifest's path; ok is false if the attempt has no snapshot, e.g.
+// because it started before the orchestrator was restarted.
+func (o *Orchestrator) endManifest(t *model.Task) (written []string, path string, ok bool) {
+	if o.manifestDir == "" || len(t.History) == 0 {
+		return nil, "", false
+	}
+	n := t.History[len(t.History)-1].Number
+	beforePath := o.manifestPath(t.ID, n, ".before")
+	before, err := manifest.LoadSnapshot(beforePath)
+	if err != nil {
+		return nil, "", false
+	}
+	after, err := manifest.Take(filepath.Join(artifact.BaseDir, t.OutputDir))
+	if err != nil {
+		log.Printf("[manifest] %s: %v", t.ID, err)
+		return nil, "", false
+	}
+
+	m := &manifest.Manifest{Task: t.ID, Attempt: n, OutputDir: t.OutputDir, Files: manifest.Diff(before, after)}
+	path = o.manifestPath(t.ID, n, "")
+	if err := manifest.Save(path, m); err != nil {
+		log.Printf("[manifest] %s: %v", t.ID, err)
+		return nil, "", false
+	}
+	_ = os.Remove(beforePath)
+	return m.Written(), path, true
+}
+
+// overlapping returns the other tasks with an attempt in t's output dir that
+// ran at any time between the start of t's current attempt and end.
+func (o *Orchestrator) overlapping(t *model.Task, end time.Time) []string {
+	if t.OutputDir == "" || len(t.History) == 0 {
+		return nil
+	}
+	start := t.History[len(t.History)-1].StartedAt
+	var ids []string
+	for _, u := range o.graph.Tasks() {
+		if u.ID == t.ID || u.OutputDir != t.OutputDir {
+			continue
+		}
+		for _, a := range u.History {
+			if a.StartedAt <= end.Unix() && (a.FinishedAt == 0 || a.FinishedAt >= start) {
+				ids = append(ids, u.ID)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// scopeReviewer points a reviewer at the files its task has written across
+// all its attempts, so it reviews those instead of the task's whole output
+// dir. A task with no recorded files, or with an attempt that overlapped
+// another task's in the same dir, leaves the reviewer unscoped.
+func (o *Orchestrator) scopeReviewer(r *model.Task) {
+	t, ok := o.graph.Snapshot(r.ReviewTaskID)
+	if !ok {
+		return
+	}
+	if slices.ContainsFunc(t.History, func(a model.Attempt) bool { return len(a.Overlapped) > 0 }) {
+		_ = o.graph.SetChanged(r.ID, nil)
+		return
+	}
+	var files []string
+	for _, a := range t.History {
+		for _, f := range a.FilesChanged {
+			p := filepath.Join(t.OutputDir, f)
+			if !slices.Contains(files, p) {
+				files = append(files, p)
+			}
+		}
+	}
+	// Files a later attempt deleted are no longer there to review.
+	files = slices.DeleteFunc(files, func(p string) bool {
+		_, err := os.Stat(filepath.Join(artifact.BaseDir, p))
+		return err != nil
+	})
+	slices.Sort(files)
+	_ = o.graph.SetChanged(r.ID, files)
+}
+
+// removeStaleSnapshots deletes the start snapshots of attempts that never
+// ended: after a restart none is in flight, so no snapshot will be diffed.
+func (o *Orchestrator) removeStaleSnapshots() {
+	if o.manifestDir == "" {
+		return
+	}
+	stale, _ := filepath.Glob(filepath.Join(o.manifestDir, "*.before.json"))
+	for _, p := range stale {
+		_ = os.Remove(p)
+	}
+}
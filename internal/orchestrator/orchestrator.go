@@ -3,18 +3,24 @@ package orchestrator
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"gopkg.in/yaml.v3"
 
 	"github.com/hubenschmidt/claude-dag/internal/agent"
 	"github.com/hubenschmidt/claude-dag/internal/artifact"
+	"github.com/hubenschmidt/claude-dag/internal/cache"
+	"github.com/hubenschmidt/claude-dag/internal/config"
 	"github.com/hubenschmidt/claude-dag/internal/model"
 	"github.com/hubenschmidt/claude-dag/internal/tmux"
+	"github.com/hubenschmidt/claude-dag/internal/watch"
 )
 
 // Roles that produce code and should be auto-reviewed.
@@ -24,10 +30,14 @@ var reviewableRoles = map[model.AgentRole]bool{
 	model.RoleDatabase: true,
 }
 
-const (
-	pollInterval = 3 * time.Second
-	maxWaves     = 50
-)
+// Roles whose tasks get a tester that derives tests from the contracts.
+var testedRoles = map[model.AgentRole]bool{
+	model.RoleBackend: true,
+}
+
+// pollInterval is how often the loop re-checks tasks when no file watcher is
+// available.
+const pollInterval = 3 * time.Second
 
 // Orchestrator manages the DAG of tasks, launching them as tmux windows
 // and polling for completion.
@@ -35,34 +45,153 @@ type Orchestrator struct {
 	session    string
 	dispatcher *Dispatcher
 	graph      *Graph
-	events     []string // recent log events shown in the DAG display
+	checkpoint string   // path the graph is saved to after every poll, if set
+	planMode   PlanMode // what to do once the task plan is expanded
+	brownfield bool     // builders change the target repository in artifacts/repo/
+	baseCommit string   // commit the target repository was checked out at
+	config     *config.Config
+
+	wake    chan struct{}  // wakes the poll loop early; see wait
+	watcher *watch.Watcher // nil when polling only
+	cache   *cache.Cache   // nil when memoization is off
+
+	// The plan is accepted on the Run goroutine and read from control
+	// socket handlers.
+	planMu       sync.Mutex
+	planAccepted bool // the plan has passed its gate; nothing is cached before
+
+	manifestDir string // where attempt snapshots and manifests are saved, if set
+
+	ctx context.Context // the run's, set by Run; ends background verify commands
+
+	verifyMu  sync.Mutex
+	verifying map[string]bool // tasks whose verify command is running
+
+	filesMu      sync.Mutex
+	listingFiles map[string]chan struct{} // closed once a task's last ended attempt has its files; see recordFiles
+	filesWG      sync.WaitGroup
+
+	// Events are logged from the poll loop and from control socket handlers.
+	eventsMu sync.Mutex
+	events   []string  // recent log events shown in the DAG display
+	eventLog io.Writer // full timestamped event history, if set
 }
 
 // New creates an orchestrator bound to a tmux session.
 func New(session string, agents []agent.Agent) *Orchestrator {
-	return &Orchestrator{
+	o := &Orchestrator{
 		session:    session,
 		dispatcher: NewDispatcher(session, agents),
 		graph:      NewGraph(),
+		config:     &config.Config{},
+		wake:       make(chan struct{}, 1),
+		verifying:  map[string]bool{},
+
+		listingFiles: map[string]chan struct{}{},
+	}
+	o.dispatcher.onStart = o.startAttempt
+	o.dispatcher.onLaunched = o.notify
+	o.dispatcher.onLaunchFailed = func(id string, err error) {
+		detail := fmt.Sprintf("launch failed: %v", err)
+		if agent.IsPromptError(err) {
+			o.failTaskForGood(id, model.StatusLaunching, model.FailLaunchError, detail)
+			return
+		}
+		o.failTask(id, model.StatusLaunching, model.FailLaunchError, detail)
+	}
+	return o
+}
+
+// SetConfig applies per-role settings from the project config.
+func (o *Orchestrator) SetConfig(cfg *config.Config) {
+	o.config = cfg
+	for role, rc := range cfg.Roles {
+		o.dispatcher.sched.setDeclared(role, rc.Duration)
+	}
+}
+
+// SetDurationHistory gives the scheduler mean per-role task durations from
+// past runs (see RoleDurations), used where the config declares none.
+func (o *Orchestrator) SetDurationHistory(h map[model.AgentRole]time.Duration) {
+	o.dispatcher.sched.setHistory(h)
+}
+
+// SetEventLog directs every event, with a timestamp, to w in addition to the
+// dashboard's recent-events list.
+func (o *Orchestrator) SetEventLog(w io.Writer) {
+	o.eventLog = w
+}
+
+// SetCheckpoint makes the orchestrator save its graph to path after every
+// poll, so other commands can inspect the run and a later process can resume it.
+func (o *Orchestrator) SetCheckpoint(path string) {
+	o.checkpoint = path
+}
+
+// Restore replaces the graph with one loaded from a checkpoint. Tasks that
+// were running when the previous process stopped lost their panes with the old
+// session: they count as completed if their sentinel exists, otherwise they
+// are re-queued, as are cancelled tasks.
+func (o *Orchestrator) Restore(g *Graph) {
+	for _, t := range g.Tasks() {
+		if t.Status == model.StatusLaunching || t.Status == model.StatusCancelled {
+			_ = g.EndAttempt(t.ID, "interrupted", "orchestrator stopped", time.Now(), nil)
+			_ = g.SetError(t.ID, "")
+			_ = g.SetPaneID(t.ID, "")
+			_ = g.SetStatus(t.ID, model.StatusPending)
+			o.logEvent("resume: re-queued %s", t.ID)
+			continue
+		}
+		if t.Status != model.StatusRunning {
+			continue
+		}
+		_ = g.SetPaneID(t.ID, "")
+		if _, err := os.Stat(sentinelPath(t.OutputDir, t.ID)); t.OutputDir != "" && err == nil {
+			_ = g.SetStatus(t.ID, model.StatusCompleted)
+			_ = g.EndAttempt(t.ID, "sentinel", "", time.Now(), nil)
+			o.logEvent("resume: %s completed while stopped", t.ID)
+			continue
+		}
+		_ = g.EndAttempt(t.ID, "interrupted", "orchestrator stopped", time.Now(), nil)
+		_ = g.SetStatus(t.ID, model.StatusPending)
+		o.logEvent("resume: re-queued %s", t.ID)
 	}
+	o.removeStaleSnapshots()
+	o.graph = g
 }
 
-// Run executes the full 5-phase orchestration.
+// Run executes the full 5-phase orchestration. Phases already finished in a
+// restored graph are skipped.
 func (o *Orchestrator) Run(ctx context.Context, goal string) error {
+	o.ctx = ctx
 	log.Printf("[orchestrator] goal: %s", goal)
+	defer func() {
+		o.filesWG.Wait() // so the checkpoint has every attempt's files
+		o.saveCheckpoint()
+	}()
+	defer func() {
+		if ctx.Err() != nil {
+			o.drain()
+		}
+	}()
 
 	// Phase 1 — Design: launch architect
 	o.logEvent("phase 1: architect design")
-	archTask := &model.Task{
-		ID:          "architect-design",
-		Role:        model.RoleArchitect,
-		Description: goal,
-		OutputDir:   "contracts",
-	}
-	if err := o.graph.AddTask(archTask); err != nil {
-		return fmt.Errorf("add architect task: %w", err)
+	if _, ok := o.graph.Get("architect-design"); !ok {
+		archTask := &model.Task{
+			ID:          "architect-design",
+			Role:        model.RoleArchitect,
+			Description: goal,
+			OutputDir:   "contracts",
+		}
+		if o.brownfield {
+			archTask.ArtifactDirs = []string{repoMapDir}
+		}
+		if err := o.addTask(archTask); err != nil {
+			return fmt.Errorf("add architect task: %w", err)
+		}
 	}
-	if err := o.dispatcher.LaunchReady(o.graph); err != nil {
+	if err := o.launchReady(); err != nil {
 		return fmt.Errorf("launch architect: %w", err)
 	}
 	if err := o.pollUntilDone(ctx, "architect-design"); err != nil {
@@ -70,10 +199,26 @@ func (o *Orchestrator) Run(ctx context.Context, goal string) error {
 	}
 
 	// Expand the architect's task plan into the DAG
-	if err := o.expandTaskPlan(); err != nil {
-		return fmt.Errorf("expand task plan: %w", err)
+	if !o.graph.PlanExpanded() {
+		if err := o.expandTaskPlan(); err != nil {
+			return fmt.Errorf("expand task plan: %w", err)
+		}
+		o.logEvent("task plan expanded, %d total tasks", len(o.graph.Tasks()))
+
+		switch o.planMode {
+		case PlanOnly:
+			o.logEvent("plan-only: stopping before build")
+			fmt.Println()
+			PrintPlan(os.Stdout, o.graph)
+			return nil
+		case PlanApprove:
+			if err := o.approvePlan(ctx); err != nil {
+				return err
+			}
+		}
 	}
-	o.logEvent("task plan expanded, %d total tasks", len(o.graph.Tasks()))
+
+	o.acceptPlan()
 
 	// Phase 2+3 — Build + Review: poll loop for sub-agents and reviewers
 	o.logEvent("phase 2-3: build + review")
@@ -89,6 +234,15 @@ func (o *Orchestrator) Run(ctx context.Context, goal string) error {
 
 	// Phase 5 — Assemble: integrator wires everything together
 	// TODO: implement integrator agent launch
+
+	// Then devops packages the project to run; an existing repository
+	// already has its own way to run.
+	if !o.brownfield {
+		o.logEvent("phase 5: devops")
+		if err := o.runDevops(ctx); err != nil {
+			return fmt.Errorf("devops: %w", err)
+		}
+	}
 	o.logEvent("all phases complete")
 	return nil
 }
@@ -96,19 +250,20 @@ func (o *Orchestrator) Run(ctx context.Context, goal string) error {
 // runArchitectValidation spawns the architect in validation mode to check
 // cross-agent coherence. If rejected, specific tasks re-enter build→review.
 func (o *Orchestrator) runArchitectValidation(ctx context.Context) error {
-	reviewDeps := o.reviewTaskIDs()
-
-	valTask := &model.Task{
-		ID:        "architect-validate",
-		Role:      model.RoleArchitect,
-		DependsOn: reviewDeps,
-		Description: "Validate that all implementations honor the original contracts",
-		OutputDir: "reviews",
-	}
-	if err := o.graph.AddTask(valTask); err != nil {
-		return fmt.Errorf("add validation task: %w", err)
+	if _, ok := o.graph.Get("architect-validate"); !ok {
+		valTask := &model.Task{
+			ID:           "architect-validate",
+			Role:         model.RoleArchitect,
+			DependsOn:    o.reviewTaskIDs(),
+			Description:  "Validate that all implementations honor the original contracts",
+			ArtifactDirs: []string{"contracts", "code/backend", "code/frontend", "schemas"},
+			OutputDir:    "reviews",
+		}
+		if err := o.addTask(valTask); err != nil {
+			return fmt.Errorf("add validation task: %w", err)
+		}
 	}
-	if err := o.dispatcher.LaunchReady(o.graph); err != nil {
+	if err := o.launchReady(); err != nil {
 		return fmt.Errorf("launch validation: %w", err)
 	}
 	if err := o.pollUntilDone(ctx, "architect-validate"); err != nil {
@@ -121,13 +276,13 @@ func (o *Orchestrator) runArchitectValidation(ctx context.Context) error {
 		return fmt.Errorf("read validation verdict: %w", err)
 	}
 
-	if parseVerdict(verdict) == "APPROVED" {
+	result, feedback := parseVerdict(verdict)
+	if result == "APPROVED" {
 		o.logEvent("architect validation: APPROVED")
 		return nil
 	}
 
-	// Rejected — extract feedback and re-queue affected tasks
-	feedback := extractFeedback(verdict)
+	// Rejected — re-queue affected tasks with the feedback
 	o.logEvent("architect validation: REJECTED — re-entering build/review")
 
 	// Reset all code-producing tasks with the architect's feedback
@@ -135,15 +290,7 @@ func (o *Orchestrator) runArchitectValidation(ctx context.Context) error {
 		if !reviewableRoles[t.Role] {
 			continue
 		}
-		_ = o.graph.RejectTask(t.ID, feedback)
-		clearSentinel(t.OutputDir, t.ID)
-		// Also reset corresponding reviewer
-		if t.ReviewTaskID == "" {
-			continue
-		}
-		_ = o.graph.SetStatus(t.ReviewTaskID, model.StatusPending)
-		_ = o.graph.SetResult(t.ReviewTaskID, "")
-		clearSentinel("reviews", t.ReviewTaskID)
+		o.sendBack(t.ID, "architect-validate", feedback)
 	}
 
 	// Reset the validation task itself so it can re-run after fixes
@@ -158,11 +305,11 @@ func (o *Orchestrator) runArchitectValidation(ctx context.Context) error {
 	return o.runArchitectValidation(ctx)
 }
 
-// reviewTaskIDs returns IDs of all reviewer tasks in the graph.
+// reviewTaskIDs returns IDs of all reviewer and tester tasks in the graph.
 func (o *Orchestrator) reviewTaskIDs() []string {
 	var ids []string
 	for _, t := range o.graph.Tasks() {
-		if t.Role != model.RoleReviewer {
+		if t.Role != model.RoleReviewer && t.Role != model.RoleTester {
 			continue
 		}
 		ids = append(ids, t.ID)
@@ -170,7 +317,7 @@ func (o *Orchestrator) reviewTaskIDs() []string {
 	return ids
 }
 
-// pollUntilDone blocks until the named task's pane exits.
+// pollUntilDone blocks until the named task completes or fails for good.
 func (o *Orchestrator) pollUntilDone(ctx context.Context, taskID string) error {
 	for {
 		if err := ctx.Err(); err != nil {
@@ -179,6 +326,14 @@ func (o *Orchestrator) pollUntilDone(ctx context.Context, taskID string) error {
 
 		o.printDAG()
 		o.reapFinished()
+		o.processFixes()
+		o.storeApproved()
+		o.saveCheckpoint()
+
+		// Relaunch the task if a failure re-queued it, or its fixer.
+		if err := o.launchReady(); err != nil {
+			return fmt.Errorf("relaunch %s: %w", taskID, err)
+		}
 
 		task, ok := o.graph.Get(taskID)
 		if !ok {
@@ -188,24 +343,31 @@ func (o *Orchestrator) pollUntilDone(ctx context.Context, taskID string) error {
 		if task.Status == model.StatusCompleted {
 			return nil
 		}
-		if task.Status == model.StatusFailed {
-			return fmt.Errorf("task %s failed: %s", taskID, task.Error)
+		if isFailure(task.Status) {
+			return fmt.Errorf("task %s %s: %s", taskID, task.Status, task.Error)
 		}
 
-		time.Sleep(pollInterval)
+		o.wait(ctx)
 	}
 }
 
-// pollLoop is the main orchestration loop for build+review phases.
+// pollLoop is the main orchestration loop for build+review phases. It runs
+// until every task completes, something fails for good, or ctx ends.
 func (o *Orchestrator) pollLoop(ctx context.Context) error {
-	for i := range maxWaves {
+	for wave := 1; ; wave++ {
 		if err := ctx.Err(); err != nil {
 			return err
 		}
 
 		o.printDAG()
 		o.reapFinished()
+		o.processFixes()
+		o.processTests()
 		o.processReviews()
+		o.requestApprovals()
+		o.storeApproved()
+		o.propagateFailures()
+		o.saveCheckpoint()
 
 		if o.graph.AllCompleted() {
 			log.Println("[orchestrator] all tasks completed")
@@ -213,37 +375,48 @@ func (o *Orchestrator) pollLoop(ctx context.Context) error {
 			return nil
 		}
 
-		// On permanent failure, ask user for feedback instead of exiting
+		// Launch any newly-ready tasks; branches untouched by a failure keep going.
+		if err := o.launchReady(); err != nil {
+			return fmt.Errorf("wave %d: %w", wave, err)
+		}
+
+		if o.canProgress() {
+			o.wait(ctx)
+			continue
+		}
+
+		// Nothing else can move. Failures are what's left: ask the user for
+		// feedback instead of exiting.
 		if o.graph.HasFailed() {
 			o.printDAG()
-			if !o.promptUserForRetry() {
+			if !o.promptUserForRetry(ctx) {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				o.graph.SkipBlocked()
+				o.saveCheckpoint()
 				return fmt.Errorf("one or more tasks failed permanently")
 			}
 			continue
 		}
-
-		// Launch any newly-ready tasks
-		if err := o.dispatcher.LaunchReady(o.graph); err != nil {
-			return fmt.Errorf("wave %d: %w", i+1, err)
-		}
-
-		// Check for deadlock: nothing running, nothing ready, not all completed
-		if !o.hasRunning() && len(o.graph.ReadyTasks()) == 0 && !o.graph.AllCompleted() {
-			return fmt.Errorf("deadlock: no running or ready tasks, but not all completed")
-		}
-
-		time.Sleep(pollInterval)
+		return fmt.Errorf("deadlock: no running or ready tasks, but not all completed")
 	}
+}
 
-	return fmt.Errorf("exceeded max polling iterations (%d)", maxWaves)
+// canProgress reports whether anything can still change without the user:
+// an agent is working, a task is ready to launch or waiting out a retry
+// backoff, or a task awaits approval.
+func (o *Orchestrator) canProgress() bool {
+	return o.hasRunning() || len(o.graph.ReadyTasks()) > 0 || !o.graph.NextRetry().IsZero() || len(o.pendingApprovals()) > 0
 }
 
-// promptUserForRetry shows failed tasks and asks the user for feedback.
-// Returns true if the user provided feedback and tasks were reset for retry.
-func (o *Orchestrator) promptUserForRetry() bool {
+// promptUserForRetry shows failed and cancelled tasks and asks the user for
+// feedback. Returns true if the user provided feedback and tasks were reset
+// for retry.
+func (o *Orchestrator) promptUserForRetry(ctx context.Context) bool {
 	fmt.Println("--- Failed Tasks ---")
 	for _, t := range o.graph.Tasks() {
-		if t.Status != model.StatusFailed {
+		if !isFailure(t.Status) {
 			continue
 		}
 		fmt.Printf("  %s: %s\n", t.ID, t.Error)
@@ -251,108 +424,116 @@ func (o *Orchestrator) promptUserForRetry() bool {
 	fmt.Println()
 	fmt.Print("Enter feedback to retry failed tasks (or 'q' to quit): ")
 
-	var input string
-	fmt.Scanln(&input)
+	input := readLine(ctx)
 
 	if input == "" || input == "q" {
 		return false
 	}
 
 	for _, t := range o.graph.Tasks() {
-		if t.Status != model.StatusFailed {
+		if !isFailure(t.Status) {
 			continue
 		}
-		t.Attempts = 0
-		t.Status = model.StatusPending
-		t.Feedback = input
-		t.Error = ""
-		clearSentinel(t.OutputDir, t.ID)
+		_ = RetryTask(o.graph, t.ID, input)
 		o.logEvent("user retry: %s", t.ID)
-
-		// Also reset corresponding reviewer if one exists
-		if t.ReviewTaskID == "" {
-			continue
-		}
-		rev, ok := o.graph.Get(t.ReviewTaskID)
-		if !ok {
-			continue
-		}
-		rev.Status = model.StatusPending
-		rev.Result = ""
-		clearSentinel("reviews", t.ReviewTaskID)
 	}
+	o.propagateFailures()
 
 	return true
 }
 
-// reapFinished checks running tasks for completion via .done sentinel files
-// or pane death (whichever comes first).
+// RetryTask re-queues a task with a fresh attempt budget and the given
+// feedback, along with its reviewers and tester, clearing their sentinels so
+// the relaunch isn't reaped immediately.
+func RetryTask(g *Graph, id, feedback string) error {
+	t, ok := g.Get(id)
+	if !ok {
+		return fmt.Errorf("task %s not found", id)
+	}
+	if err := g.Retry(id, feedback); err != nil {
+		return err
+	}
+	clearSentinel(t.OutputDir, t.ID)
+	resetChecks(g, t)
+	return nil
+}
+
+// reapFinished settles running tasks. A .done sentinel means the agent
+// finished: the task completes, after its role's verify command passes if it
+// has one. A task that outruns its role's timeout, or whose pane exits without
+// a sentinel, fails as a timeout or agent crash.
 func (o *Orchestrator) reapFinished() {
+	paneAlive := o.paneChecker()
+
+	now := time.Now()
 	for _, t := range o.graph.Tasks() {
 		if t.Status != model.StatusRunning {
 			continue
 		}
+		rc := o.config.Role(t.Role)
 
-		// Check for per-task .done sentinel file
 		if t.OutputDir != "" {
-			donePath := sentinelPath(t.OutputDir, t.ID)
-			if _, err := os.Stat(donePath); err == nil {
-				_ = o.graph.SetStatus(t.ID, model.StatusCompleted)
-				o.logEvent("task %s completed (sentinel)", t.ID)
+			if _, err := os.Stat(sentinelPath(t.OutputDir, t.ID)); err == nil {
+				if t.Role == model.RoleDevops && !o.checkDevops(t) {
+					continue
+				}
+				if verify := o.verifyCommand(t); verify != "" {
+					o.startVerify(t, verify)
+					continue
+				}
+				o.complete(t.ID, model.StatusRunning, "sentinel")
 				continue
 			}
 		}
 
-		// Fallback: pane exited
-		if t.PaneID != "" && !tmux.IsPaneAlive(t.PaneID) {
-			_ = o.graph.SetStatus(t.ID, model.StatusCompleted)
-			o.logEvent("task %s completed (pane %s exited)", t.ID, t.PaneID)
-		}
-	}
-}
-
-// processReviews checks completed reviewer tasks and either approves or
-// rejects the code task they reviewed.
-func (o *Orchestrator) processReviews() {
-	for _, t := range o.graph.Tasks() {
-		if t.Role != model.RoleReviewer || t.Status != model.StatusCompleted {
-			continue
-		}
-		if t.ReviewTaskID == "" {
+		if rc.Timeout > 0 && t.StartedAt > 0 && now.Sub(time.Unix(t.StartedAt, 0)) > rc.Timeout {
+			// Fail first so the kill below isn't mistaken for a crash.
+			if o.failTask(t.ID, model.StatusRunning, model.FailTimeout, fmt.Sprintf("ran longer than %s", rc.Timeout)) {
+				o.killPane(t.ID, t.PaneID)
+			}
 			continue
 		}
 
-		reviewed, ok := o.graph.Get(t.ReviewTaskID)
-		if !ok || reviewed.Status != model.StatusCompleted {
-			continue
+		if t.PaneID != "" && !paneAlive(t.PaneID) {
+			o.failTask(t.ID, model.StatusRunning, model.FailAgentCrash, fmt.Sprintf("pane %s exited without writing its sentinel", t.PaneID))
 		}
+	}
+}
 
-		// Read the review artifact to determine verdict
-		reviewContent, err := artifact.Read("reviews", t.ID+".md")
+// paneChecker returns a function reporting whether a pane is alive. One tmux
+// call covers every pane; it falls back to per-pane checks if that fails.
+func (o *Orchestrator) paneChecker() func(paneID string) bool {
+	live, err := tmux.LivePanes(o.session)
+	return func(paneID string) bool {
 		if err != nil {
-			log.Printf("[orchestrator] could not read review for %s: %v", t.ID, err)
-			continue
+			return tmux.IsPaneAlive(paneID)
 		}
+		return live[paneID]
+	}
+}
 
-		verdict := parseVerdict(reviewContent)
-		if verdict == "APPROVED" {
-			o.logEvent("review APPROVED: %s", t.ReviewTaskID)
-			continue
-		}
-
-		feedback := extractFeedback(reviewContent)
-		o.logEvent("review REJECTED: %s (attempt %d/%d)", t.ReviewTaskID, reviewed.Attempts+1, model.MaxAttempts)
-
-		_ = o.graph.RejectTask(t.ReviewTaskID, feedback)
-		clearSentinel(reviewed.OutputDir, t.ReviewTaskID)
-		_ = o.graph.SetStatus(t.ID, model.StatusPending)
-		_ = o.graph.SetResult(t.ID, "")
-		clearSentinel("reviews", t.ID)
+// sendBack rejects a code task with feedback from by (its reviewers,
+// its tester, architect-validate or "human") and re-queues its reviewers and
+// tester, so the task goes through build, test and review again.
+func (o *Orchestrator) sendBack(id, by, feedback string) {
+	t, ok := o.graph.Get(id)
+	if !ok {
+		return
 	}
+	_ = o.graph.SetAttemptVerdict(id, "rejected by "+by, feedback)
+	_ = o.graph.RejectTask(id, feedback)
+	o.saveFeedbackLog(id)
+	clearSentinel(t.OutputDir, id)
+	resetChecks(o.graph, t)
 }
 
 func (o *Orchestrator) logEvent(format string, args ...any) {
 	msg := fmt.Sprintf(format, args...)
+	o.eventsMu.Lock()
+	defer o.eventsMu.Unlock()
+	if o.eventLog != nil {
+		fmt.Fprintf(o.eventLog, "%s %s\n", time.Now().Format(time.RFC3339), msg)
+	}
 	o.events = append(o.events, msg)
 	// Keep last 10 events
 	if len(o.events) > 10 {
@@ -360,26 +541,51 @@ func (o *Orchestrator) logEvent(format string, args ...any) {
 	}
 }
 
-// hasRunning returns true if any task is in running status.
+// hasRunning returns true if any task is launching or running.
 func (o *Orchestrator) hasRunning() bool {
-	for _, t := range o.graph.Tasks() {
-		if t.Status == model.StatusRunning {
-			return true
-		}
-	}
-	return false
+	return o.graph.RunningCount() > 0
 }
 
-// printDAG renders the current DAG status table to stdout.
+// printDAG renders the current DAG status table and recent events to stdout.
 func (o *Orchestrator) printDAG() {
 	fmt.Println()
 	fmt.Println("=== Claude DAG ===")
 	fmt.Println()
-	fmt.Printf("%-30s %-12s %-8s %-10s\n", "Task", "Status", "Window", "Duration")
-	fmt.Println(strings.Repeat("-", 65))
+	PrintGraph(os.Stdout, o.graph)
+
+	o.printApprovals()
+	PrintBlocked(os.Stdout, o.graph)
+
+	switch {
+	case o.dispatcher.Stopped():
+		fmt.Println()
+		fmt.Println("*** shutting down: waiting for agents to wrap up (Ctrl-C again to force quit) ***")
+	case o.dispatcher.Paused():
+		fmt.Println()
+		fmt.Println("*** dispatching paused (swarm ctl resume) ***")
+	}
+
+	// Show recent events
+	o.eventsMu.Lock()
+	events := append([]string(nil), o.events...)
+	o.eventsMu.Unlock()
+	if len(events) > 0 {
+		fmt.Println()
+		fmt.Println("--- Events ---")
+		for _, e := range events {
+			fmt.Printf("  %s\n", e)
+		}
+	}
+	fmt.Println()
+}
+
+// PrintGraph writes the task status table for g to w.
+func PrintGraph(w io.Writer, g *Graph) {
+	fmt.Fprintf(w, "%-30s %-18s %-8s %-10s\n", "Task", "Status", "Window", "Duration")
+	fmt.Fprintln(w, strings.Repeat("-", 71))
 
 	now := time.Now().Unix()
-	for _, t := range o.graph.Tasks() {
+	for _, t := range g.Tasks() {
 		pane := "-"
 		if t.PaneID != "" {
 			pane = t.PaneID
@@ -387,22 +593,40 @@ func (o *Orchestrator) printDAG() {
 
 		dur := "-"
 		if t.StartedAt > 0 {
-			elapsed := time.Duration(now-t.StartedAt) * time.Second
+			end := now
+			if t.Status != model.StatusRunning && t.FinishedAt >= t.StartedAt {
+				end = t.FinishedAt
+			}
+			elapsed := time.Duration(end-t.StartedAt) * time.Second
 			dur = elapsed.Truncate(time.Second).String()
 		}
 
-		fmt.Printf("%-30s %-12s %-8s %-10s\n", t.ID, t.Status, pane, dur)
+		fmt.Fprintf(w, "%-30s %-18s %-8s %-10s\n", t.ID, t.Status, pane, dur)
 	}
+}
 
-	// Show recent events
-	if len(o.events) > 0 {
-		fmt.Println()
-		fmt.Println("--- Events ---")
-		for _, e := range o.events {
-			fmt.Printf("  %s\n", e)
-		}
+// acceptPlan records that the plan has passed its gate.
+func (o *Orchestrator) acceptPlan() {
+	o.planMu.Lock()
+	defer o.planMu.Unlock()
+	o.planAccepted = true
+}
+
+// isPlanAccepted reports whether the plan has passed its gate.
+func (o *Orchestrator) isPlanAccepted() bool {
+	o.planMu.Lock()
+	defer o.planMu.Unlock()
+	return o.planAccepted
+}
+
+// saveCheckpoint writes the graph to the checkpoint path, if one is set.
+func (o *Orchestrator) saveCheckpoint() {
+	if o.checkpoint == "" {
+		return
+	}
+	if err := o.graph.Save(o.checkpoint); err != nil {
+		log.Printf("[orchestrator] checkpoint: %v", err)
 	}
-	fmt.Println()
 }
 
 // sentinelPath returns the per-task sentinel file path: artifacts/<dir>/.done.<taskID>
@@ -419,27 +643,37 @@ func clearSentinel(outputDir, taskID string) {
 	os.Remove(sentinelPath(outputDir, taskID))
 }
 
-func parseVerdict(result string) string {
-	upper := strings.ToUpper(strings.TrimSpace(result))
-	if strings.HasPrefix(upper, "APPROVED") {
-		return "APPROVED"
-	}
-	return "REJECTED"
-}
+// verdictLine matches the line a review or validation verdict starts on: an
+// "APPROVED:" or "REJECTED:" marker, as the prompts ask for, optionally
+// emphasized or in a heading.
+var verdictLine = regexp.MustCompile(`^[\s#>*_]*(APPROVED|REJECTED)[*_]*:[*_]*\s*(.*)$`)
 
-func extractFeedback(result string) string {
-	idx := strings.Index(strings.ToUpper(result), "REJECTED:")
-	if idx == -1 {
-		return result
+// parseVerdict reads a review or validation report: "APPROVED" or
+// "REJECTED" from its first verdict line, and the body that follows the
+// marker. A report with no verdict line is a rejection with the whole report
+// as its body.
+func parseVerdict(report string) (verdict, body string) {
+	lines := strings.Split(report, "\n")
+	for i, line := range lines {
+		if m := verdictLine.FindStringSubmatch(line); m != nil {
+			body = strings.Join(append([]string{m[2]}, lines[i+1:]...), "\n")
+			return m[1], strings.TrimSpace(body)
+		}
 	}
-	return strings.TrimSpace(result[idx+9:])
+	return "REJECTED", strings.TrimSpace(report)
 }
 
 type taskPlanEntry struct {
-	ID          string   `yaml:"id"`
-	Role        string   `yaml:"role"`
-	Description string   `yaml:"description"`
-	DependsOn   []string `yaml:"depends_on"`
+	ID               string   `yaml:"id"`
+	Role             string   `yaml:"role"`
+	Description      string   `yaml:"description"`
+	DependsOn        []string `yaml:"depends_on"`
+	RequiresApproval bool     `yaml:"requires_approval"`
+	Priority         int      `yaml:"priority"`
+
+	Retry *model.RetryPolicy `yaml:"retry"` // overrides the role's retry policy
+
+	Paths []string `yaml:"paths"` // repo paths the task changes, in brownfield mode
 }
 
 // taskPlanWrapper handles the case where the architect wraps the list in a "tasks:" key.
@@ -447,6 +681,8 @@ type taskPlanWrapper struct {
 	Tasks []taskPlanEntry `yaml:"tasks"`
 }
 
+// expandTaskPlan adds every entry of the architect's task plan to the graph,
+// or, if any entry can't be added, none of them.
 func (o *Orchestrator) expandTaskPlan() error {
 	raw, err := artifact.Read("contracts", "task-plan.yaml")
 	if err != nil {
@@ -458,60 +694,79 @@ func (o *Orchestrator) expandTaskPlan() error {
 		return fmt.Errorf("parse task plan: %w", err)
 	}
 
-	roleMap := map[string]model.AgentRole{
-		"backend":    model.RoleBackend,
-		"frontend":   model.RoleFrontend,
-		"database":   model.RoleDatabase,
-		"reviewer":   model.RoleReviewer,
-		"integrator": model.RoleIntegrator,
-		"migrator":   model.RoleMigrator,
+	existing := map[string]bool{}
+	for _, t := range o.graph.Tasks() {
+		existing[t.ID] = true
 	}
-
 	for _, e := range entries {
-		role, ok := roleMap[strings.ToLower(e.Role)]
-		if !ok {
-			log.Printf("[orchestrator] skipping unknown role %q in task plan", e.Role)
-			continue
+		if err := o.addPlanEntry(e, "architect-design"); err != nil {
+			o.removeAddedSince(existing)
+			return err
 		}
+	}
+	o.graph.SetPlanExpanded()
+	return nil
+}
 
-		deps := e.DependsOn
-		if len(deps) == 0 {
-			deps = []string{"architect-design"}
-		}
+var planRoles = map[string]model.AgentRole{
+	"backend":    model.RoleBackend,
+	"frontend":   model.RoleFrontend,
+	"database":   model.RoleDatabase,
+	"reviewer":   model.RoleReviewer,
+	"integrator": model.RoleIntegrator,
+	"migrator":   model.RoleMigrator,
+}
 
-		task := &model.Task{
-			ID:           e.ID,
-			Role:         role,
-			Description:  e.Description,
-			DependsOn:    deps,
-			ArtifactDirs: artifactDirsForRole(role),
-			OutputDir:    outputDirForRole(role),
-		}
-		if err := o.graph.AddTask(task); err != nil {
-			return fmt.Errorf("add task %s: %w", e.ID, err)
-		}
+// addPlanEntry adds one task-plan entry to the graph, auto-wiring a reviewer
+// for code-producing roles and a tester for backend tasks. An entry without
+// dependencies depends on defaultDep. Entries with unknown roles are skipped.
+func (o *Orchestrator) addPlanEntry(e taskPlanEntry, defaultDep string) error {
+	role, ok := planRoles[strings.ToLower(e.Role)]
+	if !ok {
+		log.Printf("[orchestrator] skipping unknown role %q in task plan", e.Role)
+		return nil
+	}
 
-		// Auto-wire a reviewer for code-producing tasks
-		if !reviewableRoles[role] {
-			continue
-		}
-		reviewID := "review-" + e.ID
-		reviewTask := &model.Task{
-			ID:           reviewID,
-			Role:         model.RoleReviewer,
-			Description:  fmt.Sprintf("Review code produced by task %s", e.ID),
-			DependsOn:    []string{e.ID},
-			ArtifactDirs: append([]string{outputDirForRole(role)}, "contracts"),
-			OutputDir:    "reviews",
-			ReviewTaskID: e.ID,
-		}
-		if err := o.graph.AddTask(reviewTask); err != nil {
-			return fmt.Errorf("add review task %s: %w", reviewID, err)
+	if err := e.Retry.Validate(); err != nil {
+		return fmt.Errorf("task %s retry: %w", e.ID, err)
+	}
+
+	deps := e.DependsOn
+	if len(deps) == 0 {
+		deps = []string{defaultDep}
+	}
+
+	task := &model.Task{
+		ID:               e.ID,
+		Role:             role,
+		Description:      e.Description,
+		DependsOn:        deps,
+		ArtifactDirs:     artifactDirsForRole(role),
+		OutputDir:        outputDirForRole(role),
+		RequiresApproval: e.RequiresApproval || o.config.Role(role).RequiresApproval,
+		Priority:         e.Priority,
+		Retry:            e.Retry,
+	}
+	if o.brownfield && builderRoles[role] {
+		if err := targetRepo(task, e.Paths); err != nil {
+			return fmt.Errorf("task %s: %w", e.ID, err)
 		}
-		task.ReviewTaskID = reviewID
+	}
+	if err := o.addTask(task); err != nil {
+		return fmt.Errorf("add task %s: %w", e.ID, err)
 	}
 
-	return nil
+	// Auto-wire a tester for backend tasks, and a reviewer, or a review
+	// panel, for code-producing tasks
+	if testedRoles[role] {
+		if err := o.wireTester(task); err != nil {
+			return err
+		}
+	}
+	if !reviewableRoles[role] {
+		return nil
+	}
+	return o.wireReviewers(task)
 }
 
 // parseTaskPlan handles both formats: bare list and {tasks: [...]}.
@@ -537,8 +792,10 @@ func artifactDirsForRole(role model.AgentRole) []string {
 		model.RoleFrontend:   {"contracts"},
 		model.RoleDatabase:   {"contracts"},
 		model.RoleReviewer:   {"contracts", "code/backend"},
+		model.RoleTester:     {"code/backend", "contracts"},
 		model.RoleIntegrator: {"contracts", "code/backend", "code/frontend"},
 		model.RoleMigrator:   {"contracts"},
+		model.RoleDevops:     {"contracts", "code/backend", "code/frontend", "code/integrated", "schemas"},
 	}
 	return m[role]
 }
@@ -549,6 +806,8 @@ func outputDirForRole(role model.AgentRole) string {
 		model.RoleFrontend:   "code/frontend",
 		model.RoleDatabase:   "schemas",
 		model.RoleReviewer:   "reviews",
+		model.RoleTester:     "tests",
+		model.RoleDevops:     "deploy",
 		model.RoleIntegrator: "code/integrated",
 		model.RoleMigrator:   "code/migrated",
 	}
@@ -0,0 +1,192 @@
+package orchestrator
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/hubenschmidt/claude-dag/internal/artifact"
+)
+
+// PlanMode controls what happens once the architect's task plan is expanded.
+type PlanMode int
+
+const (
+	PlanAuto    PlanMode = iota // start building immediately
+	PlanOnly                    // stop after expanding and printing the DAG
+	PlanApprove                 // wait in window 0 for the user to accept the plan
+)
+
+// stdinLines delivers lines typed into window 0. A single reader goroutine
+// feeds every interactive prompt, so buffered input isn't lost between prompts
+// and a prompt can be abandoned when the run is interrupted.
+var (
+	stdinOnce  sync.Once
+	stdinLines = make(chan string)
+)
+
+// readLine reads one trimmed line of user input. It returns "" once ctx ends.
+func readLine(ctx context.Context) string {
+	stdinOnce.Do(func() {
+		go func() {
+			r := bufio.NewReader(os.Stdin)
+			for {
+				line, err := r.ReadString('\n')
+				stdinLines <- line
+				if err != nil {
+					close(stdinLines)
+					return
+				}
+			}
+		}()
+	})
+	select {
+	case <-ctx.Done():
+		return ""
+	case line := <-stdinLines:
+		return strings.TrimSpace(line)
+	}
+}
+
+// WaitForEnter blocks until the user presses Enter in window 0. Use it rather
+// than reading os.Stdin directly, which would race the prompt reader.
+func WaitForEnter() {
+	readLine(context.Background())
+}
+
+// SetPlanMode chooses whether the run builds, stops, or waits for approval
+// after the task plan is expanded.
+func (o *Orchestrator) SetPlanMode(mode PlanMode) {
+	o.planMode = mode
+}
+
+// approvePlan shows the expanded graph and contracts and loops until the user
+// accepts the plan. Editing reloads task-plan.yaml; feedback re-runs
+// architect-design. A plan can be accepted only once it has fully expanded
+// into at least one task. It returns an error if the user quits.
+func (o *Orchestrator) approvePlan(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		o.printPlanReview()
+		fmt.Print("[a]ccept, [e]dit task plan, [f]eedback to architect, [q]uit: ")
+
+		switch strings.ToLower(readLine(ctx)) {
+		case "a", "accept":
+			// A failed re-expansion or redesign is rolled back, leaving the
+			// plan unexpanded.
+			if !o.graph.PlanExpanded() || len(o.graph.Tasks()) <= 1 {
+				fmt.Println("The plan has no tasks to build: edit the task plan or send the architect feedback.")
+				continue
+			}
+			o.logEvent("plan accepted")
+			return nil
+
+		case "e", "edit":
+			if err := editFile(filepath.Join(artifact.BaseDir, "contracts", "task-plan.yaml")); err != nil {
+				fmt.Printf("editor: %v\n", err)
+				continue
+			}
+			if err := o.reexpandPlan(); err != nil {
+				fmt.Printf("reload task plan: %v\n", err)
+				continue
+			}
+			o.logEvent("task plan edited and reloaded, %d total tasks", len(o.graph.Tasks()))
+
+		case "f", "feedback":
+			fmt.Print("Feedback for the architect: ")
+			feedback := readLine(ctx)
+			if feedback == "" {
+				continue
+			}
+			if err := o.redesign(ctx, feedback); err != nil {
+				return err
+			}
+
+		case "q", "quit":
+			return fmt.Errorf("plan rejected by user")
+		}
+	}
+}
+
+// reexpandPlan discards the expanded tasks and expands task-plan.yaml again.
+// If the new plan fails to expand, every task it added is removed again and
+// the graph is left with only the design task, not expanded.
+func (o *Orchestrator) reexpandPlan() error {
+	o.graph.Prune("architect-design")
+	return o.expandTaskPlan()
+}
+
+// redesign re-runs architect-design with the user's feedback and expands the
+// revised plan.
+func (o *Orchestrator) redesign(ctx context.Context, feedback string) error {
+	o.graph.Prune("architect-design")
+	if err := RetryTask(o.graph, "architect-design", feedback); err != nil {
+		return err
+	}
+	o.logEvent("plan feedback sent to architect")
+
+	if err := o.launchReady(); err != nil {
+		return fmt.Errorf("relaunch architect: %w", err)
+	}
+	if err := o.pollUntilDone(ctx, "architect-design"); err != nil {
+		return fmt.Errorf("architect redesign: %w", err)
+	}
+	if err := o.expandTaskPlan(); err != nil {
+		// Leave the user in the approval loop to edit or retry.
+		fmt.Printf("expand revised task plan: %v\n", err)
+		return nil
+	}
+	o.logEvent("revised plan expanded, %d total tasks", len(o.graph.Tasks()))
+	return nil
+}
+
+// printPlanReview shows everything the user needs to judge the plan.
+func (o *Orchestrator) printPlanReview() {
+	fmt.Println()
+	fmt.Println("=== Plan Review ===")
+	for _, name := range []string{"api-contract.yaml", "data-model.yaml"} {
+		content, err := artifact.Read("contracts", name)
+		if err != nil {
+			continue
+		}
+		fmt.Printf("\n--- %s ---\n%s\n", name, strings.TrimSpace(content))
+	}
+	fmt.Println()
+	fmt.Println("--- Expanded DAG ---")
+	PrintPlan(os.Stdout, o.graph)
+	fmt.Println()
+}
+
+// PrintPlan writes each task with its role and dependencies to w.
+func PrintPlan(w io.Writer, g *Graph) {
+	fmt.Fprintf(w, "%-30s %-10s %s\n", "Task", "Role", "Depends On")
+	fmt.Fprintln(w, strings.Repeat("-", 65))
+	for _, t := range g.Tasks() {
+		deps := "-"
+		if len(t.DependsOn) > 0 {
+			deps = strings.Join(t.DependsOn, ", ")
+		}
+		fmt.Fprintf(w, "%-30s %-10s %s\n", t.ID, t.Role, deps)
+	}
+}
+
+// editFile opens path in $EDITOR (vi if unset) attached to the terminal.
+func editFile(path string) error {
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,153 @@
+package orchestrator
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hubenschmidt/claude-dag/internal/artifact"
+	"github.com/hubenschmidt/claude-dag/internal/model"
+)
+
+// verifyTimeout bounds a role's verify command.
+const verifyTimeout = 10 * time.Minute
+
+// addTask adds t to the graph with its retry policy resolved: the config's
+// policy for its role, overridden by anything the task itself sets.
+func (o *Orchestrator) addTask(t *model.Task) error {
+	policy := o.config.RetryPolicy(t.Role).With(t.Retry)
+	t.Retry = &policy
+	return o.graph.AddTask(t)
+}
+
+// failTask records a failed attempt of a task still in status from, closing
+// it in the task's history and logging whether it will be retried. It
+// reports whether the task was still in from.
+func (o *Orchestrator) failTask(id string, from model.TaskStatus, class model.FailureClass, detail string) bool {
+	return o.recordFailure(id, from, class, detail, o.graph.Fail)
+}
+
+// failTaskForGood is failTask for a failure that would recur on every
+// attempt, such as a prompt that can't be built: the task fails whatever
+// its retry policy.
+func (o *Orchestrator) failTaskForGood(id string, from model.TaskStatus, class model.FailureClass, detail string) bool {
+	return o.recordFailure(id, from, class, detail, o.graph.FailForGood)
+}
+
+func (o *Orchestrator) recordFailure(id string, from model.TaskStatus, class model.FailureClass, detail string, fail func(string, model.TaskStatus, model.FailureClass, string) bool) bool {
+	t, ok := o.graph.Snapshot(id)
+	if !ok || !fail(id, from, class, detail) {
+		return false
+	}
+	o.endAttempt(id, string(class), summarize(detail))
+	if class == model.FailVerification {
+		_ = o.graph.SetAttemptVerdict(id, model.VerdictFailedVerification, detail)
+		o.saveFeedbackLog(id)
+	}
+	clearSentinel(t.OutputDir, id)
+
+	after, _ := o.graph.Snapshot(id)
+	if after.Status == model.StatusFailed {
+		o.logEvent("task %s failed: %s", id, after.Error)
+		return true
+	}
+	wait := ""
+	if after.RetryAfter > time.Now().Unix() {
+		wait = fmt.Sprintf(" in %s", time.Until(time.Unix(after.RetryAfter, 0)).Round(time.Second))
+	}
+	o.logEvent("task %s: %s (%s), retrying%s", id, class, summarize(detail), wait)
+	return true
+}
+
+// startVerify runs the role's verify command for a task whose agent has
+// finished, in the background. The task stays running until the command
+// settles it as completed, hands it to a fixer, or fails its verification,
+// or until the run shuts down, which cancels it.
+func (o *Orchestrator) startVerify(t *model.Task, command string) {
+	o.verifyMu.Lock()
+	if o.verifying[t.ID] {
+		o.verifyMu.Unlock()
+		return
+	}
+	o.verifying[t.ID] = true
+	o.verifyMu.Unlock()
+
+	id, role, dir := t.ID, t.Role, filepath.Join(artifact.BaseDir, t.OutputDir)
+	o.logEvent("verifying %s: %s", id, command)
+	go func() {
+		defer func() {
+			o.verifyMu.Lock()
+			delete(o.verifying, id)
+			o.verifyMu.Unlock()
+			o.notify()
+		}()
+
+		ctx := cmp.Or(o.ctx, context.Background())
+		out, err := runVerify(ctx, command, dir, id)
+		if err != nil && ctx.Err() != nil {
+			// Shutting down: settle the task now, or every wake-up of the
+			// drain would verify it again.
+			o.cancel(t, model.StatusRunning, "cancelled", "verification stopped by shutdown")
+			return
+		}
+		if err != nil {
+			detail := fmt.Sprintf("Verification command `%s` failed: %v\n%s", command, err, lastLines(out, 40))
+			if role != model.RoleFixer && o.startFixer(id, detail) {
+				return
+			}
+			o.failTask(id, model.StatusRunning, model.FailVerification, detail)
+			return
+		}
+		o.complete(id, model.StatusRunning, "verified")
+	}()
+}
+
+// runVerify runs command with sh in dir and returns its combined output.
+// It is killed if ctx ends or verifyTimeout passes.
+func runVerify(ctx context.Context, command, dir, taskID string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, verifyTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "SWARM_TASK="+taskID, "SWARM_ARTIFACTS="+artifact.BaseDir)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		log.Printf("[verify] %s: %v", taskID, err)
+		return out.String(), err
+	}
+	return out.String(), nil
+}
+
+// complete marks a task in status from completed, noting how completion was
+// detected. A sub-plan the task emitted is spliced in first; an invalid one
+// fails the attempt instead.
+func (o *Orchestrator) complete(id string, from model.TaskStatus, how string) {
+	if err := o.spliceSubPlan(id); err != nil {
+		o.failTask(id, from, model.FailVerification, err.Error())
+		return
+	}
+	if o.graph.Transition(id, from, model.StatusCompleted) {
+		_ = o.graph.SetFinishedAt(id, time.Now().Unix())
+		o.endAttempt(id, how, "")
+		o.logEvent("task %s completed (%s)", id, how)
+	}
+}
+
+// lastLines returns at most the final n lines of s.
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
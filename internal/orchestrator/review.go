@@ -0,0 +1,272 @@
+package orchestrator
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/hubenschmidt/claude-dag/internal/agent"
+	"github.com/hubenschmidt/claude-dag/internal/artifact"
+	"github.com/hubenschmidt/claude-dag/internal/model"
+)
+
+// review is one reviewer's verdict on a code task.
+type review struct {
+	taskID   string
+	lens     string
+	approved bool
+	blocking bool // raised an issue that must be fixed
+	feedback string
+}
+
+// wireReviewers adds the reviewers of a code task: the role's configured
+// panel, one reviewer per lens, or else a single generic reviewer.
+func (o *Orchestrator) wireReviewers(t *model.Task) error {
+	rc := o.config.Role(t.Role).Review
+	if rc == nil || len(rc.Lenses) == 0 {
+		reviewID := "review-" + t.ID
+		if err := o.addTask(reviewerTask(t, reviewID, "", nil)); err != nil {
+			return fmt.Errorf("add review task %s: %w", reviewID, err)
+		}
+		if err := o.graph.SetReviewTaskID(t.ID, reviewID); err != nil {
+			return err
+		}
+		if rc != nil && rc.Consensus != "" {
+			return o.graph.SetPanel(t.ID, nil, rc.Consensus)
+		}
+		return nil
+	}
+
+	ids := make([]string, 0, len(rc.Lenses))
+	for _, lens := range rc.Lenses {
+		if _, err := agent.ResolvePrompt(lensPrompt(lens)); err != nil {
+			return fmt.Errorf("review lens %s: %w", lens, err)
+		}
+		reviewID := fmt.Sprintf("review-%s-%s", t.ID, lens)
+		if err := o.addTask(reviewerTask(t, reviewID, lens, rc.Lenses)); err != nil {
+			return fmt.Errorf("add review task %s: %w", reviewID, err)
+		}
+		ids = append(ids, reviewID)
+	}
+	return o.graph.SetPanel(t.ID, ids, cmp.Or(rc.Consensus, model.ConsensusAll))
+}
+
+// reviewerTask builds the reviewer task for code task t, focused on lens if
+// it belongs to a panel of lenses.
+func reviewerTask(t *model.Task, id, lens string, lenses []string) *model.Task {
+	desc := fmt.Sprintf("Review code produced by task %s", t.ID)
+	if lens != "" {
+		desc += fmt.Sprintf(" (%s lens)", lens)
+	}
+	return &model.Task{
+		ID:           id,
+		Role:         model.RoleReviewer,
+		Description:  desc,
+		DependsOn:    []string{t.ID},
+		ArtifactDirs: append(scopeDirs(t), "contracts"),
+		OutputDir:    "reviews",
+		ReviewTaskID: t.ID,
+		Priority:     t.Priority,
+		Lens:         lens,
+		Lenses:       slices.Clone(lenses),
+	}
+}
+
+// lensPrompt names the prompt file that focuses a panel reviewer.
+func lensPrompt(lens string) string {
+	return "review-" + lens
+}
+
+// resetChecks re-queues every reviewer of t, and its tester, for a fresh
+// look at the reworked output.
+func resetChecks(g *Graph, t *model.Task) {
+	for _, id := range t.Reviewers() {
+		_ = g.SetStatus(id, model.StatusPending)
+		_ = g.SetResult(id, "")
+		clearSentinel("reviews", id)
+	}
+	if t.TestTaskID != "" && t.Role != model.RoleTester {
+		_ = g.SetStatus(t.TestTaskID, model.StatusPending)
+		_ = g.SetResult(t.TestTaskID, "")
+		clearSentinel(outputDirForRole(model.RoleTester), t.TestTaskID)
+	}
+}
+
+// processReviews settles completed code tasks whose reviewers have all
+// finished. Their verdicts combine under the task's consensus rule: the task
+// is approved, or sent back with every rejecting reviewer's feedback merged.
+func (o *Orchestrator) processReviews() {
+	for _, t := range o.graph.Tasks() {
+		reviewers := t.Reviewers()
+		if len(reviewers) == 0 || t.Status != model.StatusCompleted {
+			continue
+		}
+		if n := len(t.History); n > 0 && strings.HasPrefix(t.History[n-1].Verdict, "approved by") {
+			// Settled: the verdict is recorded, so the reviews aren't read again.
+			o.holdIfSignOffDue(t)
+			continue
+		}
+		reviews, ok := o.collectReviews(reviewers)
+		if !ok {
+			continue
+		}
+
+		approved, by := decide(t.Consensus, reviews)
+		if approved {
+			_ = o.graph.SetAttemptVerdict(t.ID, "approved by "+by, "")
+			o.logEvent("review APPROVED: %s", t.ID)
+			o.holdIfSignOffDue(t)
+			continue
+		}
+
+		o.logEvent("review REJECTED: %s by %s (attempt %d/%d)", t.ID, by, t.Attempts+1, t.RetryPolicy().MaxAttempts)
+		o.sendBack(t.ID, by, mergeFeedback(reviews))
+	}
+}
+
+// holdIfSignOffDue holds an approved task for human sign-off once its tests
+// have passed, if it needs one.
+func (o *Orchestrator) holdIfSignOffDue(t *model.Task) {
+	if t.RequiresApproval && !t.Approved && o.testsPassed(t) {
+		o.holdForApproval(t.ID)
+	}
+}
+
+// collectReviews reads the verdict of every reviewer, reporting false while
+// any of them hasn't finished.
+func (o *Orchestrator) collectReviews(ids []string) ([]review, bool) {
+	reviews := make([]review, 0, len(ids))
+	for _, id := range ids {
+		rt, ok := o.graph.Get(id)
+		if !ok || rt.Status != model.StatusCompleted {
+			return nil, false
+		}
+		content, err := artifact.Read("reviews", id+".md")
+		if err != nil {
+			log.Printf("[orchestrator] could not read review for %s: %v", id, err)
+			return nil, false
+		}
+		reviews = append(reviews, parseReview(id, rt.Lens, content))
+	}
+	return reviews, true
+}
+
+// parseReview reads a reviewer's verdict and the severity of the issues
+// listed after it. An issue's tag counts only at its head, so a tag quoted
+// in its text doesn't change it. A rejection is blocking unless every issue
+// it lists is tagged [minor]; an approval only if one is [blocking].
+func parseReview(id, lens, content string) review {
+	verdict, body := parseVerdict(content)
+	r := review{
+		taskID:   id,
+		lens:     lens,
+		approved: verdict == "APPROVED",
+		feedback: body,
+	}
+	issues := splitIssues(body)
+	r.blocking = !r.approved && len(issues) == 0
+	for _, issue := range issues {
+		switch tag := issueTag.FindStringSubmatch(issue); {
+		case tag != nil && strings.EqualFold(tag[1], "blocking"):
+			r.blocking = true
+		case tag == nil && !r.approved:
+			r.blocking = true
+		}
+	}
+	return r
+}
+
+var (
+	// listItem matches the start of an issue in a numbered or bulleted list.
+	listItem = regexp.MustCompile(`^\s*(?:\d+[.)]|[-*+])\s+`)
+	// issueTag matches the severity tag an issue starts with.
+	issueTag = regexp.MustCompile(`(?i)^[*_` + "`" + `]*\[(blocking|minor)\]`)
+)
+
+// splitIssues returns the issues a verdict's body lists, one per list item.
+// Text before the list introduces it; with no list, that text is the one
+// issue.
+func splitIssues(body string) []string {
+	var lead string
+	var issues []string
+	for _, l := range strings.Split(body, "\n") {
+		l = strings.TrimSpace(l)
+		switch {
+		case l == "":
+		case listItem.MatchString(l):
+			issues = append(issues, listItem.ReplaceAllString(l, ""))
+		case len(issues) == 0:
+			lead = strings.TrimSpace(lead + " " + l)
+		default:
+			issues[len(issues)-1] += " " + l
+		}
+	}
+	if len(issues) == 0 && lead != "" {
+		issues = []string{lead}
+	}
+	return issues
+}
+
+// decide applies a consensus rule to a set of reviews. It reports whether
+// the task is approved and who decided: the rejecting reviewers, or a
+// description of the approval.
+func decide(consensus model.Consensus, reviews []review) (bool, string) {
+	var approvals int
+	var rejecting []string
+	for _, r := range reviews {
+		if r.approved {
+			approvals++
+		}
+		if sendsBack(consensus, r) {
+			rejecting = append(rejecting, r.taskID)
+		}
+	}
+
+	var approved bool
+	switch consensus {
+	case model.ConsensusMajority:
+		approved = approvals*2 > len(reviews)
+	case model.ConsensusBlocking:
+		approved = len(rejecting) == 0
+	default:
+		approved = approvals == len(reviews)
+	}
+
+	if !approved {
+		return false, strings.Join(rejecting, ", ")
+	}
+	if len(reviews) == 1 {
+		return true, reviews[0].taskID
+	}
+	return true, fmt.Sprintf("%s panel (%d/%d approved)", cmp.Or(consensus, model.ConsensusAll), approvals, len(reviews))
+}
+
+// sendsBack reports whether r's issues count toward a rejection under consensus.
+func sendsBack(consensus model.Consensus, r review) bool {
+	if consensus == model.ConsensusBlocking {
+		return r.blocking
+	}
+	return !r.approved
+}
+
+// mergeFeedback combines the feedback of every reviewer that didn't approve,
+// one headed section per reviewer. A lone reviewer's feedback is used as is.
+func mergeFeedback(reviews []review) string {
+	if len(reviews) == 1 {
+		return reviews[0].feedback
+	}
+	var b strings.Builder
+	for _, r := range reviews {
+		if r.approved && !r.blocking {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "=== %s review (%s) ===\n%s\n", cmp.Or(r.lens, "general"), r.taskID, strings.TrimSpace(r.feedback))
+	}
+	return b.String()
+}
@@ -0,0 +1,93 @@
+package orchestrator
+
+import (
+	"testing"
+
+	"github.com/hubenschmidt/claude-dag/internal/model"
+)
+
+func TestDecide(t *testing.T) {
+	approve := func(id string) review { return review{taskID: id, approved: true} }
+	reject := func(id string, blocking bool) review { return review{taskID: id, blocking: blocking} }
+
+	tests := []struct {
+		name      string
+		consensus model.Consensus
+		reviews   []review
+		approved  bool
+		by        string
+	}{
+		{"single reviewer approves", "", []review{approve("r1")}, true, "r1"},
+		{"single reviewer rejects", "", []review{reject("r1", true)}, false, "r1"},
+		{"all: every reviewer approves", model.ConsensusAll, []review{approve("a"), approve("b")}, true, "all panel (2/2 approved)"},
+		{"all: one rejection sends back", model.ConsensusAll, []review{approve("a"), reject("b", false)}, false, "b"},
+		{"default rule is all", "", []review{approve("a"), approve("b"), approve("c")}, true, "all panel (3/3 approved)"},
+		{"majority: two of three", model.ConsensusMajority, []review{approve("a"), reject("b", true), approve("c")}, true, "majority panel (2/3 approved)"},
+		{"majority: half isn't enough", model.ConsensusMajority, []review{approve("a"), reject("b", true)}, false, "b"},
+		{"majority: rejecting reviewers named", model.ConsensusMajority, []review{reject("a", false), approve("b"), reject("c", true)}, false, "a, c"},
+		{"blocking: minor rejections pass", model.ConsensusBlocking, []review{reject("a", false), approve("b")}, true, "blocking panel (1/2 approved)"},
+		{"blocking: a blocking issue sends back", model.ConsensusBlocking, []review{reject("a", false), reject("b", true)}, false, "b"},
+		{"blocking: an approval raising a blocking issue", model.ConsensusBlocking, []review{{taskID: "a", approved: true, blocking: true}, approve("b")}, false, "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			approved, by := decide(tt.consensus, tt.reviews)
+			if approved != tt.approved || by != tt.by {
+				t.Errorf("decide() = %v, %q; want %v, %q", approved, by, tt.approved, tt.by)
+			}
+		})
+	}
+}
+
+func TestParseReview(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		approved bool
+		blocking bool
+	}{
+		{"approval", "APPROVED: handlers match the contract", true, false},
+		{"approval after a heading", "# Review\n\n**APPROVED**: looks good", true, false},
+		{"approval raising a blocking issue", "APPROVED: fine overall\n1. [blocking] main.go: port hard-coded", true, true},
+		{"untagged rejection", "REJECTED: handlers.go: missing GET /items", false, true},
+		{"all issues minor", "REJECTED:\n1. [minor] handlers.go: typo in log\n2. **[minor]** store.go: unused var", false, false},
+		{"intro line before minor issues", "REJECTED: a few nits:\n- [minor] a.go: naming\n- [Minor] b.go: comment", false, false},
+		{"one blocking among minor", "REJECTED:\n1. [minor] a.go: naming\n2. [blocking] b.go: SQL injection", false, true},
+		{"one untagged among minor", "REJECTED:\n1. [minor] a.go: naming\n2. b.go: nil dereference", false, true},
+		{"tag quoted mid-issue doesn't count", "REJECTED:\n1. b.go: logs every [minor] error as fatal", false, true},
+		{"continuation lines join their issue", "REJECTED:\n1. [minor] a.go: the handler\n   logs twice", false, false},
+		{"verdict not on the first line", "Checked all handlers.\n\nREJECTED:\n1. [minor] a.go: naming", false, false},
+		{"prose starting with the verdict word", "Rejected inputs are logged.\n\nAPPROVED: looks good", true, false},
+		{"no verdict", "The code looks fine. [minor] nits only.", false, true},
+		{"verdict word without its marker", "APPROVED\nall good", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := parseReview("r", "", tt.content)
+			if r.approved != tt.approved || r.blocking != tt.blocking {
+				t.Errorf("parseReview() approved = %v, blocking = %v; want %v, %v", r.approved, r.blocking, tt.approved, tt.blocking)
+			}
+		})
+	}
+}
+
+func TestParseVerdict(t *testing.T) {
+	tests := []struct {
+		name, report, verdict, body string
+	}{
+		{"approved", "APPROVED: all endpoints match", "APPROVED", "all endpoints match"},
+		{"rejected with a list", "REJECTED:\n1. [blocking] a.go: bad\n", "REJECTED", "1. [blocking] a.go: bad"},
+		{"emphasized marker", "## Verdict\n**REJECTED:** missing handler", "REJECTED", "missing handler"},
+		{"prose before the verdict", "Approved endpoints were checked.\nREJECTED: b.go leaks", "REJECTED", "b.go leaks"},
+		{"lower case isn't a marker", "approved: fine", "REJECTED", "approved: fine"},
+		{"no marker", "Looks good to me.", "REJECTED", "Looks good to me."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verdict, body := parseVerdict(tt.report)
+			if verdict != tt.verdict || body != tt.body {
+				t.Errorf("parseVerdict() = %q, %q; want %q, %q", verdict, body, tt.verdict, tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package orchestrator
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hubenschmidt/claude-dag/internal/model"
+)
+
+// defaultTaskDuration is assumed for roles with neither a declared nor a
+// historical duration.
+const defaultTaskDuration = 5 * time.Minute
+
+// scheduler orders ready tasks so that long dependency chains are not starved
+// by independent leaf tasks: plan priority first, then critical path length,
+// then insertion order.
+type scheduler struct {
+	mu       sync.Mutex
+	declared map[model.AgentRole]time.Duration // from config, wins over history
+	history  map[model.AgentRole]time.Duration // mean duration in past runs
+}
+
+// launchDecision is a ready task chosen for launch and why it ranked where it did.
+type launchDecision struct {
+	task   *model.Task
+	reason string
+}
+
+// criticalPath is the longest chain of estimated remaining work starting at a task.
+type criticalPath struct {
+	length time.Duration
+	next   string // next task on the chain, "" at the end
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{
+		declared: map[model.AgentRole]time.Duration{},
+		history:  map[model.AgentRole]time.Duration{},
+	}
+}
+
+func (s *scheduler) setDeclared(role model.AgentRole, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.declared[role] = d
+}
+
+func (s *scheduler) setHistory(h map[model.AgentRole]time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.history = h
+}
+
+// estimate returns the expected duration of one task of role and where the
+// figure came from.
+func (s *scheduler) estimate(role model.AgentRole) (time.Duration, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d, ok := s.declared[role]; ok && d > 0 {
+		return d, "declared"
+	}
+	if d, ok := s.history[role]; ok && d > 0 {
+		return d, "historical"
+	}
+	return defaultTaskDuration, "default"
+}
+
+// order ranks ready tasks for launch.
+func (s *scheduler) order(g *Graph, ready []*model.Task) []launchDecision {
+	paths := g.criticalPaths(func(t *model.Task) time.Duration {
+		if t.Status == model.StatusCompleted {
+			return 0
+		}
+		d, _ := s.estimate(t.Role)
+		return d
+	})
+
+	ranked := make([]*model.Task, len(ready))
+	copy(ranked, ready)
+	// Stable keeps insertion order as the final tie-breaker.
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		return paths[a.ID].length > paths[b.ID].length
+	})
+
+	decisions := make([]launchDecision, 0, len(ranked))
+	for _, t := range ranked {
+		own, source := s.estimate(t.Role)
+		cp := paths[t.ID]
+		reason := fmt.Sprintf("priority %d, critical path %s (own %s %s", t.Priority, cp.length, own, source)
+		if cp.next != "" {
+			reason += ", then " + cp.next
+		}
+		decisions = append(decisions, launchDecision{task: t, reason: reason + ")"})
+	}
+	return decisions
+}
+
+// criticalPaths computes, for every task, the longest chain of estimated work
+// from the task through its transitive dependents.
+func (g *Graph) criticalPaths(estimate func(*model.Task) time.Duration) map[string]criticalPath {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	dependents := make(map[string][]string, len(g.tasks))
+	for _, id := range g.order {
+		for _, dep := range g.tasks[id].DependsOn {
+			dependents[dep] = append(dependents[dep], id)
+		}
+	}
+
+	paths := make(map[string]criticalPath, len(g.tasks))
+	visiting := map[string]bool{}
+	var walk func(id string) time.Duration
+	walk = func(id string) time.Duration {
+		if p, ok := paths[id]; ok {
+			return p.length
+		}
+		if visiting[id] {
+			return 0 // a cycle can't happen via AddTask; don't recurse forever if it does
+		}
+		visiting[id] = true
+
+		var best criticalPath
+		for _, child := range dependents[id] {
+			if l := walk(child); l > best.length || best.next == "" {
+				best = criticalPath{length: l, next: child}
+			}
+		}
+		best.length += estimate(g.tasks[id])
+		paths[id] = best
+		delete(visiting, id)
+		return best.length
+	}
+	for _, id := range g.order {
+		walk(id)
+	}
+	return paths
+}
+
+// RoleDurations averages how long completed tasks of each role took across
+// the given graphs (typically checkpoints of past runs).
+func RoleDurations(graphs []*Graph) map[model.AgentRole]time.Duration {
+	total := map[model.AgentRole]time.Duration{}
+	count := map[model.AgentRole]int{}
+	for _, g := range graphs {
+		for _, t := range g.Tasks() {
+			if t.Status != model.StatusCompleted || t.StartedAt == 0 || t.FinishedAt < t.StartedAt {
+				continue
+			}
+			total[t.Role] += time.Duration(t.FinishedAt-t.StartedAt) * time.Second
+			count[t.Role]++
+		}
+	}
+
+	mean := make(map[model.AgentRole]time.Duration, len(total))
+	for role, sum := range total {
+		mean[role] = sum / time.Duration(count[role])
+	}
+	return mean
+}
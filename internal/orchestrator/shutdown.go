@@ -0,0 +1,104 @@
+package orchestrator
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/hubenschmidt/claude-dag/internal/model"
+	"github.com/hubenschmidt/claude-dag/internal/tmux"
+)
+
+// ShutdownGrace is how long a graceful shutdown waits for running agents to
+// finish before cancelling them.
+var ShutdownGrace = 2 * time.Minute
+
+// wrapUpMessage is typed into every running agent when the run shuts down.
+const wrapUpMessage = "This run is shutting down. Finish the file you are writing, skip any remaining work, and create your .done sentinel file now."
+
+// drain winds the run down once its context ends: nothing new launches,
+// running agents are asked to wrap up and given ShutdownGrace to finish, and
+// whatever is still active after that is cancelled. An agent that wraps up
+// has cut its work short, so its attempt is recorded as interrupted and the
+// task cancelled rather than completed. Pending tasks stay pending so a
+// resumed run picks them up.
+func (o *Orchestrator) drain() {
+	o.dispatcher.Stop()
+	o.cancelActive(model.StatusLaunching, "cancelled during shutdown")
+	o.reapFinished() // agents that finished before the shutdown still count
+
+	running := 0
+	for _, t := range o.graph.Tasks() {
+		if t.Status != model.StatusRunning || t.PaneID == "" {
+			continue
+		}
+		running++
+		if err := tmux.SendText(t.PaneID, wrapUpMessage); err != nil {
+			log.Printf("[orchestrator] ask %s to wrap up: %v", t.ID, err)
+		}
+	}
+	if running > 0 {
+		o.logEvent("shutting down: waiting up to %s for %d agent(s)", ShutdownGrace, running)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ShutdownGrace)
+	defer cancel()
+	for o.hasRunning() && ctx.Err() == nil {
+		o.printDAG()
+		o.wait(ctx)
+		o.reapWrappedUp()
+	}
+
+	o.cancelActive(model.StatusRunning, "did not finish before shutdown")
+	o.logEvent("shut down")
+	o.printDAG()
+}
+
+// Abort cancels every launching or running task and checkpoints the graph at
+// once. It is for a forced quit, where the tmux session is killed right after.
+func (o *Orchestrator) Abort() {
+	o.dispatcher.Stop()
+	o.cancelActive(model.StatusLaunching, "force-quit")
+	o.cancelActive(model.StatusRunning, "force-quit")
+	o.saveCheckpoint()
+}
+
+// cancelActive marks every task in status from as cancelled with reason and
+// closes its pane. A cancelled launch closes its own pane when it settles.
+func (o *Orchestrator) cancelActive(from model.TaskStatus, reason string) {
+	for _, t := range o.graph.Tasks() {
+		o.cancel(t, from, "cancelled", reason)
+	}
+}
+
+// reapWrappedUp cancels running tasks whose agent has stopped during a
+// shutdown, by writing its sentinel as asked or by exiting.
+func (o *Orchestrator) reapWrappedUp() {
+	paneAlive := o.paneChecker()
+	for _, t := range o.graph.Tasks() {
+		if t.Status != model.StatusRunning {
+			continue
+		}
+		_, err := os.Stat(sentinelPath(t.OutputDir, t.ID))
+		stopped := t.OutputDir != "" && err == nil
+		if stopped || t.PaneID != "" && !paneAlive(t.PaneID) {
+			o.cancel(t, model.StatusRunning, "interrupted", "wrapped up during shutdown")
+		}
+	}
+}
+
+// cancel marks t cancelled if it is still in status from, ending its attempt
+// with outcome. Its sentinel is cleared and its pane closed, so a resumed run
+// relaunches it from scratch.
+func (o *Orchestrator) cancel(t *model.Task, from model.TaskStatus, outcome, reason string) {
+	if !o.graph.Transition(t.ID, from, model.StatusCancelled) {
+		return
+	}
+	_ = o.graph.SetError(t.ID, reason)
+	_ = o.graph.SetFinishedAt(t.ID, time.Now().Unix())
+	o.endAttempt(t.ID, outcome, reason)
+	clearSentinel(t.OutputDir, t.ID)
+	o.killPane(t.ID, t.PaneID)
+	o.logEvent("cancelled %s: %s", t.ID, reason)
+}
@@ -0,0 +1,170 @@
+package orchestrator
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hubenschmidt/claude-dag/internal/artifact"
+	"github.com/hubenschmidt/claude-dag/internal/model"
+)
+
+// subPlanDir holds the sub-plans tasks emit, artifacts/plans/<task>.yaml,
+// in the same schema as task-plan.yaml.
+const subPlanDir = "plans"
+
+// MaxSubPlanTasks caps the entries of a single sub-plan.
+var MaxSubPlanTasks = 10
+
+// spliceSubPlan splices the sub-plan task id emitted, if any, into the graph
+// before the task completes: its entries become children of the task, and the
+// task's dependents wait on them too. The sub-plan goes in whole or not at
+// all; an invalid one is removed and reported as an error. A task's sub-plan
+// is spliced once, so one rewritten by a later attempt is ignored, and logged.
+func (o *Orchestrator) spliceSubPlan(id string) error {
+	t, ok := o.graph.Snapshot(id)
+	if !ok {
+		return nil
+	}
+	path := filepath.Join(artifact.BaseDir, subPlanDir, id+".yaml")
+	if len(t.Children) > 0 {
+		if info, err := os.Stat(path); err == nil && len(t.History) > 0 && info.ModTime().Unix() >= t.History[len(t.History)-1].StartedAt {
+			o.logEvent("ignoring sub-plan rewritten by %s: already split into %s", id, strings.Join(t.Children, ", "))
+		}
+		return nil
+	}
+	raw, err := artifact.Read(subPlanDir, id+".yaml")
+	if err != nil {
+		return nil // no sub-plan
+	}
+
+	entries, err := o.validateSubPlan(&t, raw)
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("invalid sub-plan artifacts/%s/%s.yaml: %w", subPlanDir, id, err)
+	}
+
+	// Adding an entry also wires its reviewers and tester, and can still fail
+	// on them; take out everything added so far if it does.
+	existing := map[string]bool{}
+	for _, t := range o.graph.Tasks() {
+		existing[t.ID] = true
+	}
+	children := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if err := o.addPlanEntry(e, id); err != nil {
+			o.removeAddedSince(existing)
+			os.Remove(path)
+			return fmt.Errorf("splice sub-plan of %s: %w", id, err)
+		}
+		children = append(children, e.ID)
+	}
+	keep := map[string]bool{t.TestTaskID: true, t.FixTaskID: true}
+	for _, r := range t.Reviewers() {
+		keep[r] = true
+	}
+	if err := o.graph.Splice(id, children, keep); err != nil {
+		return err
+	}
+	o.logEvent("task %s split into %s", id, strings.Join(children, ", "))
+	return nil
+}
+
+// removeAddedSince removes every task not in existing, dependents first.
+func (o *Orchestrator) removeAddedSince(existing map[string]bool) {
+	tasks := o.graph.Tasks()
+	for i := len(tasks) - 1; i >= 0; i-- {
+		if !existing[tasks[i].ID] {
+			if err := o.graph.RemoveTask(tasks[i].ID); err != nil {
+				log.Printf("[orchestrator] roll back added tasks: %v", err)
+			}
+		}
+	}
+}
+
+// validateSubPlan parses a sub-plan emitted by parent and checks it can be
+// spliced in: known roles, fresh IDs, dependencies on parent, sibling entries
+// or tasks outside parent's downstream, and no cycles. It returns the entries
+// in an order each can be added in.
+func (o *Orchestrator) validateSubPlan(parent *model.Task, raw string) ([]taskPlanEntry, error) {
+	entries, err := parseTaskPlan(raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) > MaxSubPlanTasks {
+		return nil, fmt.Errorf("%d tasks, at most %d allowed", len(entries), MaxSubPlanTasks)
+	}
+
+	byID := map[string]*taskPlanEntry{}
+	for i := range entries {
+		e := &entries[i]
+		role, ok := planRoles[strings.ToLower(e.Role)]
+		switch {
+		case e.ID == "":
+			return nil, fmt.Errorf("entry %d has no id", i+1)
+		case byID[e.ID] != nil:
+			return nil, fmt.Errorf("duplicate id %s", e.ID)
+		case !ok || !o.dispatcher.HasAgent(role):
+			return nil, fmt.Errorf("task %s: no agent for role %q", e.ID, e.Role)
+		}
+		if _, exists := o.graph.Get(e.ID); exists {
+			return nil, fmt.Errorf("task %s already exists", e.ID)
+		}
+		if err := e.Retry.Validate(); err != nil {
+			return nil, fmt.Errorf("task %s retry: %w", e.ID, err)
+		}
+		if o.brownfield && builderRoles[role] {
+			if err := targetRepo(&model.Task{}, e.Paths); err != nil {
+				return nil, fmt.Errorf("task %s: %w", e.ID, err)
+			}
+		}
+		byID[e.ID] = e
+	}
+
+	downstream := o.graph.Downstream(parent.ID)
+	for _, e := range entries {
+		for _, dep := range e.DependsOn {
+			if byID[dep] != nil {
+				continue
+			}
+			if _, exists := o.graph.Get(dep); !exists {
+				return nil, fmt.Errorf("task %s depends on unknown task %s", e.ID, dep)
+			}
+			if downstream[dep] {
+				return nil, fmt.Errorf("task %s depends on %s, which waits on %s", e.ID, dep, parent.ID)
+			}
+		}
+	}
+
+	// Order entries so each comes after its sibling dependencies.
+	ordered := make([]taskPlanEntry, 0, len(entries))
+	state := map[string]int{} // 1 visiting, 2 done
+	var visit func(e *taskPlanEntry) error
+	visit = func(e *taskPlanEntry) error {
+		switch state[e.ID] {
+		case 1:
+			return fmt.Errorf("dependency cycle through %s", e.ID)
+		case 2:
+			return nil
+		}
+		state[e.ID] = 1
+		for _, dep := range e.DependsOn {
+			if sib := byID[dep]; sib != nil {
+				if err := visit(sib); err != nil {
+					return err
+				}
+			}
+		}
+		state[e.ID] = 2
+		ordered = append(ordered, *e)
+		return nil
+	}
+	for i := range entries {
+		if err := visit(&entries[i]); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
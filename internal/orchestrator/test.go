@@ -0,0 +1,88 @@
+package orchestrator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hubenschmidt/claude-dag/internal/artifact"
+	"github.com/hubenschmidt/claude-dag/internal/model"
+)
+
+// testPassed is the Result of a tester whose report has been read and passed.
+const testPassed = "passed"
+
+// wireTester adds a tester for code task t. It depends on t, so it runs
+// alongside t's reviewers once the code is written.
+func (o *Orchestrator) wireTester(t *model.Task) error {
+	testID := "test-" + t.ID
+	tester := &model.Task{
+		ID:           testID,
+		Role:         model.RoleTester,
+		Description:  fmt.Sprintf("Write and run tests for the code produced by task %s", t.ID),
+		DependsOn:    []string{t.ID},
+		ArtifactDirs: append(scopeDirs(t), "contracts"),
+		OutputDir:    outputDirForRole(model.RoleTester),
+		TestTaskID:   t.ID,
+		Priority:     t.Priority,
+	}
+	if err := o.addTask(tester); err != nil {
+		return fmt.Errorf("add test task %s: %w", testID, err)
+	}
+	return o.graph.SetTestTaskID(t.ID, testID)
+}
+
+// processTests reads the report of every finished tester. Failing tests send
+// the code task back with the failures as rework feedback, the same way a
+// reviewer rejection does. A missing report counts as a failure too; sending
+// the task back re-queues the tester, so each report is handled once.
+func (o *Orchestrator) processTests() {
+	for _, t := range o.graph.Tasks() {
+		if t.Role == model.RoleTester || t.TestTaskID == "" || t.Status != model.StatusCompleted {
+			continue
+		}
+		tt, ok := o.graph.Get(t.TestTaskID)
+		if !ok || tt.Status != model.StatusCompleted || tt.Result == testPassed {
+			continue
+		}
+
+		passed, summary := false, ""
+		report, err := artifact.Read(tt.OutputDir, tt.ID+".md")
+		if err != nil {
+			summary = fmt.Sprintf("Tester %s finished without a readable report at artifacts/%s/%s.md (%v); the tests must be run and reported again.", tt.ID, tt.OutputDir, tt.ID, err)
+		} else {
+			passed, summary = parseTestReport(report)
+		}
+		if passed {
+			_ = o.graph.SetResult(tt.ID, testPassed)
+			o.logEvent("tests PASSED: %s", t.ID)
+			continue
+		}
+		o.logEvent("tests FAILED: %s (attempt %d/%d)", t.ID, t.Attempts+1, t.RetryPolicy().MaxAttempts)
+		o.sendBack(t.ID, tt.ID, summary)
+	}
+}
+
+// testsPassed reports whether t's tester, if it has one, has passed the
+// current output.
+func (o *Orchestrator) testsPassed(t *model.Task) bool {
+	if t.TestTaskID == "" {
+		return true
+	}
+	tt, ok := o.graph.Get(t.TestTaskID)
+	return ok && tt.Result == testPassed
+}
+
+// parseTestReport reads a tester's report: "PASSED: <summary>" or
+// "FAILED: <failing tests and their output>". Anything else counts as a
+// failure, with the whole report as feedback.
+func parseTestReport(report string) (bool, string) {
+	trimmed := strings.TrimSpace(report)
+	if strings.HasPrefix(strings.ToUpper(trimmed), "PASSED") {
+		return true, trimmed
+	}
+	idx := strings.Index(strings.ToUpper(trimmed), "FAILED:")
+	if idx == -1 {
+		return false, trimmed
+	}
+	return false, strings.TrimSpace(trimmed[idx+len("FAILED:"):])
+}
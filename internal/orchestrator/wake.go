@@ -0,0 +1,116 @@
+package orchestrator
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hubenschmidt/claude-dag/internal/artifact"
+	"github.com/hubenschmidt/claude-dag/internal/tmux"
+	"github.com/hubenschmidt/claude-dag/internal/watch"
+)
+
+const (
+	// fallbackPollInterval replaces pollInterval once file watching is active;
+	// polling then only catches events the watcher missed.
+	fallbackPollInterval = 15 * time.Second
+
+	// wakeDebounce lets a burst of file writes settle into one poll.
+	wakeDebounce = 500 * time.Millisecond
+)
+
+// StartWatching switches the poll loop to event-driven wake-ups: inotify on
+// every task's output directory, plus tmux hooks that touch a file in wakeDir
+// whenever a pane exits. On failure the orchestrator keeps polling.
+func (o *Orchestrator) StartWatching(wakeDir string) error {
+	w, err := watch.New()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(wakeDir, 0o755); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Add(wakeDir); err != nil {
+		w.Close()
+		return err
+	}
+	if err := tmux.TouchOnPaneExit(o.session, filepath.Join(wakeDir, "pane-exited")); err != nil {
+		w.Close()
+		return err
+	}
+
+	o.watcher = w
+	go func() {
+		for range w.C { // ends when StopWatching closes w
+			o.notify()
+		}
+	}()
+	return nil
+}
+
+// StopWatching closes the file watcher, if any.
+func (o *Orchestrator) StopWatching() {
+	if o.watcher != nil {
+		o.watcher.Close()
+	}
+}
+
+// watchOutputs makes sure every task's output directory exists and is
+// watched, so its .done sentinel wakes the loop the moment it appears.
+func (o *Orchestrator) watchOutputs() {
+	if o.watcher == nil {
+		return
+	}
+	for _, t := range o.graph.Tasks() {
+		if t.OutputDir == "" {
+			continue
+		}
+		dir := filepath.Join(artifact.BaseDir, t.OutputDir)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			log.Printf("[orchestrator] create %s: %v", dir, err)
+			continue
+		}
+		if err := o.watcher.Add(dir); err != nil {
+			log.Printf("[orchestrator] %v", err)
+		}
+	}
+}
+
+// notify wakes the poll loop without blocking; wake-ups coalesce.
+func (o *Orchestrator) notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// wait blocks until something may have changed — a watched file, a pane
+// exit, a finished launch, a control command, a retry backoff ending — or the
+// poll interval passes.
+func (o *Orchestrator) wait(ctx context.Context) {
+	o.watchOutputs()
+
+	interval := pollInterval
+	if o.watcher != nil {
+		interval = fallbackPollInterval
+	}
+	if next := o.graph.NextRetry(); !next.IsZero() {
+		interval = min(interval, time.Until(next))
+	}
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	case <-o.wake:
+		timer.Reset(wakeDebounce)
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+	}
+}
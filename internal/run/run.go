@@ -0,0 +1,216 @@
+// Package run manages per-run directories under .swarm/runs/. Each run owns
+// its artifacts, prompt files, event log, agent logs and final summary, so
+// state from one run can never leak into the next.
+package run
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/hubenschmidt/claude-dag/internal/brownfield"
+)
+
+// RootDir holds one subdirectory per run, relative to the working directory.
+const RootDir = ".swarm/runs"
+
+const (
+	latestLink = "latest"
+	metaFile   = "run.json"
+	idLayout   = "20060102-150405"
+)
+
+// Outcome is the final (or current) state of a run.
+type Outcome string
+
+const (
+	OutcomeRunning   Outcome = "running"
+	OutcomeSucceeded Outcome = "succeeded"
+	OutcomeFailed    Outcome = "failed"
+	OutcomeCancelled Outcome = "cancelled"
+	OutcomePlanned   Outcome = "planned" // stopped after planning (--plan-only)
+)
+
+// Run is the metadata persisted to <run dir>/run.json.
+type Run struct {
+	ID        string    `json:"id"`
+	Goal      string    `json:"goal"`
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at,omitzero"`
+	Outcome   Outcome   `json:"outcome"`
+	Error     string    `json:"error,omitempty"`
+
+	// Target is the existing repository a brownfield run changes; nil for
+	// a greenfield run.
+	Target *brownfield.Target `json:"target,omitempty"`
+}
+
+// New allocates a fresh run directory for goal and points the latest link at it.
+func New(goal string) (*Run, error) {
+	now := time.Now()
+	r := &Run{
+		ID:        now.Format(idLayout),
+		Goal:      goal,
+		StartedAt: now,
+		Outcome:   OutcomeRunning,
+	}
+
+	// Two runs started within the same second get a numeric suffix.
+	for i := 2; dirExists(r.Dir()); i++ {
+		r.ID = fmt.Sprintf("%s-%d", now.Format(idLayout), i)
+	}
+
+	for _, dir := range []string{r.ArtifactsDir(), r.PromptsDir(), r.LogsDir()} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("mkdir %s: %w", dir, err)
+		}
+	}
+	if err := r.Save(); err != nil {
+		return nil, err
+	}
+	if err := r.linkLatest(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Load reads a run by ID. The ID "latest" (or "") resolves the latest link.
+func Load(id string) (*Run, error) {
+	if id == "" || id == latestLink {
+		target, err := os.Readlink(filepath.Join(RootDir, latestLink))
+		if err != nil {
+			return nil, fmt.Errorf("no latest run: %w", err)
+		}
+		id = filepath.Base(target)
+	}
+
+	path := filepath.Join(RootDir, id, metaFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	var r Run
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// List returns every recorded run, newest first.
+func List() ([]*Run, error) {
+	entries, err := os.ReadDir(RootDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("readdir %s: %w", RootDir, err)
+	}
+
+	var runs []*Run
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		r, err := Load(e.Name())
+		if err != nil {
+			continue // not a run directory, or a half-created one
+		}
+		runs = append(runs, r)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt.After(runs[j].StartedAt) })
+	return runs, nil
+}
+
+// Save writes the run metadata.
+func (r *Run) Save() error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(r.Dir(), metaFile), data, 0o644)
+}
+
+// Finish records the outcome and end time and saves the metadata.
+func (r *Run) Finish(outcome Outcome, runErr error) error {
+	r.Outcome = outcome
+	r.EndedAt = time.Now()
+	if runErr != nil {
+		r.Error = runErr.Error()
+	}
+	return r.Save()
+}
+
+// Reopen marks a stopped run as running again and makes it the latest run.
+func (r *Run) Reopen() error {
+	r.Outcome = OutcomeRunning
+	r.EndedAt = time.Time{}
+	r.Error = ""
+	if err := r.Save(); err != nil {
+		return err
+	}
+	return r.linkLatest()
+}
+
+// Duration is the wall time of a finished run, or the time elapsed so far.
+func (r *Run) Duration() time.Duration {
+	end := r.EndedAt
+	if end.IsZero() {
+		end = time.Now()
+	}
+	return end.Sub(r.StartedAt).Truncate(time.Second)
+}
+
+// Dir is the run's root directory. Agents run with it as their working
+// directory, so "artifacts/..." paths in prompts resolve inside the run.
+func (r *Run) Dir() string { return filepath.Join(RootDir, r.ID) }
+
+// ArtifactsDir holds everything the agents produce.
+func (r *Run) ArtifactsDir() string { return filepath.Join(r.Dir(), "artifacts") }
+
+// PromptsDir holds the per-task prompt files handed to each agent.
+func (r *Run) PromptsDir() string { return filepath.Join(r.Dir(), "prompts") }
+
+// LogsDir holds the orchestrator log and a transcript of every agent pane.
+func (r *Run) LogsDir() string { return filepath.Join(r.Dir(), "logs") }
+
+// EventsPath is the append-only orchestration event log.
+func (r *Run) EventsPath() string { return filepath.Join(r.Dir(), "events.log") }
+
+// StatePath is the graph checkpoint the orchestrator rewrites on every poll.
+func (r *Run) StatePath() string { return filepath.Join(r.Dir(), "state.json") }
+
+// WorktreeDir is where a brownfield run checks out its target repository.
+func (r *Run) WorktreeDir() string { return filepath.Join(r.ArtifactsDir(), "repo") }
+
+// PatchesDir receives a brownfield run's changes as a patch set.
+func (r *Run) PatchesDir() string { return filepath.Join(r.Dir(), "patches") }
+
+// ControlPath is the orchestrator's control socket while the run is live.
+func (r *Run) ControlPath() string { return filepath.Join(r.Dir(), "control.sock") }
+
+// SummaryPath is the final task summary written when the run ends.
+func (r *Run) SummaryPath() string { return filepath.Join(r.Dir(), "summary.txt") }
+
+// linkLatest atomically repoints RootDir/latest at this run.
+func (r *Run) linkLatest() error {
+	link := filepath.Join(RootDir, latestLink)
+	tmp := link + ".tmp"
+	_ = os.Remove(tmp)
+	if err := os.Symlink(r.ID, tmp); err != nil {
+		return fmt.Errorf("link latest: %w", err)
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		return fmt.Errorf("link latest: %w", err)
+	}
+	return nil
+}
+
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
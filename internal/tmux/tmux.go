@@ -15,11 +15,18 @@ type PaneInfo struct {
 	Dead bool
 }
 
-// PromptDir returns a stable temp directory for prompt files.
+// promptDir is where WritePromptFile puts prompt files.
+var promptDir = filepath.Join(os.TempDir(), "claude-dag")
+
+// SetPromptDir changes the directory prompt files are written to.
+func SetPromptDir(dir string) {
+	promptDir = dir
+}
+
+// PromptDir returns the directory for prompt files, creating it if needed.
 func PromptDir() string {
-	dir := filepath.Join(os.TempDir(), "claude-dag")
-	os.MkdirAll(dir, 0o755)
-	return dir
+	os.MkdirAll(promptDir, 0o755)
+	return promptDir
 }
 
 // CreateSession creates a detached tmux session with a default shell.
@@ -41,8 +48,13 @@ func ConfigureSession(name string) error {
 }
 
 // NewWindow creates a named tmux window (tab) in the session without stealing focus.
-func NewWindow(session, name, cmd string) (string, error) {
-	out, err := output("new-window", "-d", "-t", session, "-n", name, "-P", "-F", "#{pane_id}", cmd)
+// If dir is non-empty the window starts in that directory.
+func NewWindow(session, name, dir, cmd string) (string, error) {
+	args := []string{"new-window", "-d", "-t", session, "-n", name, "-P", "-F", "#{pane_id}"}
+	if dir != "" {
+		args = append(args, "-c", dir)
+	}
+	out, err := output(append(args, cmd)...)
 	if err != nil {
 		return "", fmt.Errorf("new window: %w", err)
 	}
@@ -50,34 +62,56 @@ func NewWindow(session, name, cmd string) (string, error) {
 	return paneID, nil
 }
 
-// WritePromptFile writes a prompt to a temp file and returns the path.
-func WritePromptFile(taskID, promptText string) (string, error) {
-	promptPath := filepath.Join(PromptDir(), taskID+".md")
+// PromptPath returns where the prompt for a task's given attempt is written.
+// Each attempt gets its own file so earlier prompts stay inspectable.
+func PromptPath(taskID string, attempt int) string {
+	return filepath.Join(PromptDir(), fmt.Sprintf("%s.%d.md", taskID, max(attempt, 1)))
+}
+
+// WritePromptFile writes the prompt for a task's attempt and returns the path.
+func WritePromptFile(taskID string, attempt int, promptText string) (string, error) {
+	promptPath := PromptPath(taskID, attempt)
 	if err := os.WriteFile(promptPath, []byte(promptText), 0o644); err != nil {
 		return "", fmt.Errorf("write prompt file: %w", err)
 	}
 	return promptPath, nil
 }
 
-// NewAutoWindow creates a named tmux window running cmd, waits for the TUI
-// to initialize, then types the message and presses Enter separately to
-// ensure the TUI processes them correctly.
-func NewAutoWindow(session, name, cmd, initialMsg string) (string, error) {
-	paneID, err := NewWindow(session, name, cmd)
+// LaunchTimeout bounds how long NewAutoWindow waits for the Claude Code TUI
+// to accept input.
+var LaunchTimeout = 90 * time.Second
+
+const (
+	readyPollInterval = 250 * time.Millisecond
+	echoTimeout       = 5 * time.Second
+)
+
+// readyMarkers are shown by the Claude Code TUI once its input box is live.
+var readyMarkers = []string{"? for shortcuts", "│ > "}
+
+// NewAutoWindow creates a named tmux window running cmd, waits until the TUI
+// is ready for input, then types the message and presses Enter separately to
+// ensure the TUI processes them correctly. If the TUI never becomes ready the
+// window is killed and the error includes the pane's last output.
+func NewAutoWindow(session, name, dir, cmd, initialMsg string) (string, error) {
+	paneID, err := NewWindow(session, name, dir, cmd)
 	if err != nil {
 		return "", err
 	}
 
-	// Wait for Claude Code TUI to fully initialize
-	time.Sleep(5 * time.Second)
+	if err := waitForReady(paneID, LaunchTimeout); err != nil {
+		_ = KillPane(paneID)
+		return "", err
+	}
 
 	// Send text literally (without key-name interpretation)
 	if err := run("send-keys", "-t", paneID, "-l", initialMsg); err != nil {
 		return "", fmt.Errorf("send text: %w", err)
 	}
 
-	// Brief pause so TUI processes the text before Enter
-	time.Sleep(500 * time.Millisecond)
+	// Let the TUI echo the text before Enter, or it may be swallowed. A slow
+	// echo isn't fatal: submit anyway once the wait runs out.
+	_ = waitForContent(paneID, echoTimeout, firstWords(initialMsg, 4))
 
 	// Submit
 	if err := run("send-keys", "-t", paneID, "Enter"); err != nil {
@@ -87,6 +121,72 @@ func NewAutoWindow(session, name, cmd, initialMsg string) (string, error) {
 	return paneID, nil
 }
 
+// waitForReady polls the pane until a ready marker is on screen and the
+// screen has stopped changing between two polls.
+func waitForReady(paneID string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	var prev string
+	for time.Now().Before(deadline) {
+		screen, err := CapturePane(paneID)
+		if err != nil {
+			return fmt.Errorf("pane %s exited before the TUI was ready: %w", paneID, err)
+		}
+		if screen == prev && containsAny(screen, readyMarkers) {
+			return nil
+		}
+		prev = screen
+		time.Sleep(readyPollInterval)
+	}
+	return fmt.Errorf("claude TUI in pane %s not ready after %s; last output:\n%s", paneID, timeout, lastLines(prev, 10))
+}
+
+// waitForContent polls the pane until it shows text.
+func waitForContent(paneID string, timeout time.Duration, text string) error {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		screen, err := CapturePane(paneID)
+		if err != nil {
+			return err
+		}
+		if strings.Contains(screen, text) {
+			return nil
+		}
+		time.Sleep(readyPollInterval)
+	}
+	return fmt.Errorf("pane %s did not show %q after %s", paneID, text, timeout)
+}
+
+// CapturePane returns the visible contents of a pane.
+func CapturePane(paneID string) (string, error) {
+	return output("capture-pane", "-p", "-t", paneID)
+}
+
+func containsAny(s string, subs []string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstWords returns up to n leading words of s, enough to recognize it on screen.
+func firstWords(s string, n int) string {
+	words := strings.Fields(s)
+	if len(words) > n {
+		words = words[:n]
+	}
+	return strings.Join(words, " ")
+}
+
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n "), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
 // IsPaneAlive returns true if the pane's process is still running.
 func IsPaneAlive(paneID string) bool {
 	out, err := output("list-panes", "-a", "-F", "#{pane_id} #{pane_dead}", "-f", fmt.Sprintf("#{==:#{pane_id},%s}", paneID))
@@ -100,6 +200,50 @@ func IsPaneAlive(paneID string) bool {
 	return !strings.HasSuffix(trimmed, "1")
 }
 
+// PipePane appends everything the pane prints to the file at path.
+func PipePane(paneID, path string) error {
+	return run("pipe-pane", "-o", "-t", paneID, "cat >> "+ShellEscape(path))
+}
+
+// LivePanes returns the IDs of every pane in the session whose process is
+// still running, using a single tmux call for all windows.
+func LivePanes(session string) (map[string]bool, error) {
+	out, err := output("list-panes", "-s", "-t", session, "-F", "#{pane_id} #{pane_dead}")
+	if err != nil {
+		return nil, fmt.Errorf("list panes: %w", err)
+	}
+	live := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		parts := strings.Fields(line)
+		if len(parts) == 2 && parts[1] == "0" {
+			live[parts[0]] = true
+		}
+	}
+	return live, nil
+}
+
+// TouchOnPaneExit installs session hooks that touch path whenever a pane
+// exits, so a file watcher can notice immediately.
+func TouchOnPaneExit(session, path string) error {
+	cmd := "run-shell " + ShellEscape("touch "+ShellEscape(path))
+	for _, hook := range []string{"pane-exited", "pane-died"} {
+		if err := run("set-hook", "-t", session, hook, cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// KillPane terminates a pane and the process running in it.
+func KillPane(paneID string) error {
+	return run("kill-pane", "-t", paneID)
+}
+
+// HasSession reports whether the named session exists.
+func HasSession(name string) bool {
+	return run("has-session", "-t", name) == nil
+}
+
 // KillSession destroys the entire tmux session.
 func KillSession(name string) error {
 	return run("kill-session", "-t", name)
@@ -126,9 +270,23 @@ func ListPanes(session string) ([]PaneInfo, error) {
 	return panes, nil
 }
 
-// SendKeys sends keystrokes to a pane.
-func SendKeys(paneID, keys string) error {
-	return run("send-keys", "-t", paneID, keys, "Enter")
+// SendText types text into a pane literally, without key-name
+// interpretation, and submits it with Enter.
+func SendText(paneID, text string) error {
+	if err := run("send-keys", "-t", paneID, "-l", text); err != nil {
+		return fmt.Errorf("send text: %w", err)
+	}
+	_ = waitForContent(paneID, echoTimeout, firstWords(text, 4))
+	if err := run("send-keys", "-t", paneID, "Enter"); err != nil {
+		return fmt.Errorf("send enter: %w", err)
+	}
+	return nil
+}
+
+// ShellEscape wraps a string in single quotes, escaping internal single
+// quotes, for use as one word of a shell command.
+func ShellEscape(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "'\"'\"'") + "'"
 }
 
 func run(args ...string) error {
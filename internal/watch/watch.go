@@ -0,0 +1,26 @@
+// Package watch wakes the orchestrator when files appear in watched
+// directories. It only reports that something changed, not what: callers
+// re-scan their state on every wake.
+package watch
+
+// Watcher delivers a coalesced wake-up on C whenever a watched directory
+// changes. C is closed after Close.
+type Watcher struct {
+	C <-chan struct{}
+
+	c chan struct{}
+	impl
+}
+
+func newWatcher() *Watcher {
+	c := make(chan struct{}, 1)
+	return &Watcher{C: c, c: c}
+}
+
+// wake signals C without blocking; pending wake-ups collapse into one.
+func (w *Watcher) wake() {
+	select {
+	case w.c <- struct{}{}:
+	default:
+	}
+}
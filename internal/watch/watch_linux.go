@@ -0,0 +1,74 @@
+//go:build linux
+
+package watch
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// watchMask covers files being created, renamed into place, written, or
+// touched — every way an agent can produce a .done sentinel.
+const watchMask = syscall.IN_CREATE | syscall.IN_MOVED_TO | syscall.IN_CLOSE_WRITE | syscall.IN_ATTRIB
+
+type impl struct {
+	file *os.File
+	fd   int
+
+	mu      sync.Mutex
+	watched map[string]bool
+}
+
+// New starts an inotify watcher.
+func New() (*Watcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify init: %w", err)
+	}
+
+	w := newWatcher()
+	// A non-blocking fd wrapped in os.File uses the runtime poller, so Close
+	// unblocks the reader goroutine.
+	w.file = os.NewFile(uintptr(fd), "inotify")
+	w.fd = fd
+	w.watched = map[string]bool{}
+	go w.read()
+	return w, nil
+}
+
+// Add watches dir (non-recursively). Adding a directory twice is a no-op.
+func (w *Watcher) Add(dir string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.watched[dir] {
+		return nil
+	}
+	if _, err := syscall.InotifyAddWatch(w.fd, dir, watchMask); err != nil {
+		return fmt.Errorf("watch %s: %w", dir, err)
+	}
+	w.watched[dir] = true
+	return nil
+}
+
+// Close stops the watcher; C is closed once its reader has stopped.
+func (w *Watcher) Close() error {
+	return w.file.Close()
+}
+
+// read wakes C on every batch of events until the watcher is closed, then
+// closes C. It is the only sender on C.
+func (w *Watcher) read() {
+	defer close(w.c)
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			return
+		}
+		if n > 0 {
+			w.wake()
+		}
+	}
+}
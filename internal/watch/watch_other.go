@@ -0,0 +1,18 @@
+//go:build !linux
+
+package watch
+
+import "errors"
+
+type impl struct{}
+
+// New reports that file watching is unavailable; callers fall back to polling.
+func New() (*Watcher, error) {
+	return nil, errors.New("file watching is only supported on linux")
+}
+
+// Add is never reached on this platform.
+func (w *Watcher) Add(dir string) error { return nil }
+
+// Close is never reached on this platform.
+func (w *Watcher) Close() error { return nil }
@@ -0,0 +1,11 @@
+// Package prompts embeds the default system prompt for each agent role so the
+// binary works regardless of the directory it is launched from.
+package prompts
+
+import "embed"
+
+// FS holds the shipped <role>.md prompt files and the review-<lens>.md
+// prompts that focus panel reviewers.
+//
+//go:embed *.md
+var FS embed.FS